@@ -1,26 +1,410 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/notrightending/gonerator/internal/generator"
 )
 
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Println("Usage: generator <input_file> <output_file>")
+	inputFile := flag.String("input", "", "path to the Go source file, comma-separated files of one package, directory, glob, or - for stdin")
+	outputFile := flag.String("output", "", "path to the generated file, - for stdout, or a file name pattern for directory input")
+	packageName := flag.String("pkg", "", "package name of the generated file (defaults to the input package)")
+	openAPIFile := flag.String("openapi", "", "path to write an OpenAPI 3.0 spec of the parsed methods to")
+	postmanFile := flag.String("postman", "", "path to write a Postman v2.1 collection of the parsed methods to")
+	jsonSchemaDir := flag.String("jsonschema", "", "directory to write a JSON Schema of the input type of every method to")
+	clientFile := flag.String("client", "", "path to write a typed Go client for the parsed methods to, in a package of its own named after its directory")
+	mocks := flag.Bool("mocks", false, "write interfaces and mocks of the receiver types to <output>_mock.go")
+	templateFile := flag.String("template", "", "path of a text/template to generate the handlers with instead of the built-in one")
+	corsOrigin := flag.String("cors", "", "allowed origin of cross-origin requests, e.g. * (answers OPTIONS preflight requests)")
+	collectErrors := flag.Bool("collect-errors", false, "return the messages of all invalid parameters in an \"errors\" array instead of the first one")
+	hideInternalErrors := flag.Bool("hide-internal-errors", false, "answer errors with a 5xx status with a generic message and log the error instead")
+	envelope := flag.String("envelope", "", "shape of success responses: response (default), data or bare")
+	errorKey := flag.String("error-key", "", "JSON key of error messages (defaults to error)")
+	responseKey := flag.String("response-key", "", "JSON key results are wrapped in (defaults to the -envelope name)")
+	xml := flag.Bool("xml", false, "answer requests preferring application/xml in the Accept header with XML")
+	gzip := flag.Bool("gzip", false, "compress responses with gzip if the client accepts it")
+	otel := flag.Bool("otel", false, "trace every request in an OpenTelemetry span named after its route (requires go.opentelemetry.io/otel)")
+	requestID := flag.Bool("request-id", false, "read X-Request-ID or generate one, echo it in the response and pass it to the API methods in the context")
+	slog := flag.Bool("slog", false, "like -request-id, and also pass a *slog.Logger with the request ID to the API methods in the context")
+	introspect := flag.Bool("introspect", false, "serve a JSON description of the API methods at /_introspect")
+	healthz := flag.Bool("healthz", false, "serve a liveness endpoint at /healthz")
+	router := flag.String("router", "", "router to generate an adapter for: chi or gin")
+	metrics := flag.Bool("metrics", false, "collect Prometheus metrics of every request (requires github.com/prometheus/client_golang)")
+	logging := flag.Bool("logging", false, "log the method, path, status and duration of every request")
+	noRecover := flag.Bool("no-recover", false, "let panics of API methods propagate instead of answering with 500")
+	split := flag.Bool("split", false, "write the handlers of every receiver type to <receiver>_gen.go in the -output directory")
+	watch := flag.Bool("watch", false, "keep running and regenerate the outputs whenever the input files change")
+	check := flag.Bool("check", false, "compare the generated code with the existing output files and print a diff instead of writing them, exiting with 1 if they differ")
+	lax := flag.Bool("lax", false, "warn instead of failing if the input type of a method is not found, and generate its handler without parameters")
+	dump := flag.Bool("dump", false, "print the parsed methods as JSON to stdout instead of generating")
+	lint := flag.Bool("lint", false, "print warnings about suspicious annotations to stderr instead of generating")
+	lintStrict := flag.Bool("lint-strict", false, "like -lint, but exit with 1 if there are warnings")
+	strictMethods := flag.Bool("strict-methods", false, "answer disallowed methods with 405 and an Allow header instead of 406")
+	var exclude, corsMethods, corsHeaders stringsFlag
+	flag.Var(&corsMethods, "cors-methods", "method to allow in OPTIONS preflight responses instead of the methods each URL accepts (repeatable)")
+	flag.Var(&corsHeaders, "cors-headers", "header to allow in OPTIONS preflight responses besides the headers each URL reads (repeatable)")
+	flag.Var(&exclude, "exclude", fmt.Sprintf("glob of paths to skip when walking a directory input, matched relative to it or against base names (repeatable; %s are always skipped)", strings.Join(generator.DefaultExclude, " and ")))
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: generator [flags] [<input_file> <output_file>]\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Positional arguments are used when -input or -output are not set.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Under go:generate, -input and -pkg default to $GOFILE and $GOPACKAGE.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "If -output is not set, <input>_gen.go next to the input file is used.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "With -input -, the source is read from stdin and written to stdout by default.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "If the input is a directory or glob, -output is a file name pattern where\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "{name} is replaced with each input file name (default %q).\n\n", generator.DefaultOutPattern)
+		fmt.Fprintf(flag.CommandLine.Output(), "Flags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Fall back to positional arguments for backward compatibility
+	args := flag.Args()
+	if *inputFile == "" && len(args) > 0 {
+		*inputFile = args[0]
+		args = args[1:]
+	}
+	if *outputFile == "" && len(args) > 0 {
+		*outputFile = args[0]
+	}
+
+	// When run via go:generate, default to the file and package that
+	// contain the directive
+	if *inputFile == "" {
+		*inputFile = os.Getenv("GOFILE")
+	}
+	if *packageName == "" {
+		*packageName = os.Getenv("GOPACKAGE")
+	}
+
+	if *inputFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := generator.Options{
+		PackageName:        *packageName,
+		OpenAPIFile:        *openAPIFile,
+		PostmanFile:        *postmanFile,
+		JSONSchemaDir:      *jsonSchemaDir,
+		ClientFile:         *clientFile,
+		TemplateFile:       *templateFile,
+		StrictMethods:      *strictMethods,
+		NoRecover:          *noRecover,
+		Logging:            *logging,
+		Metrics:            *metrics,
+		CORSOrigin:         *corsOrigin,
+		CORSMethods:        corsMethods,
+		CORSHeaders:        corsHeaders,
+		CollectErrors:      *collectErrors,
+		Router:             *router,
+		Introspect:         *introspect,
+		Healthz:            *healthz,
+		HideInternalErrors: *hideInternalErrors,
+		Envelope:           *envelope,
+		ErrorKey:           *errorKey,
+		ResponseKey:        *responseKey,
+		XML:                *xml,
+		Gzip:               *gzip,
+		Slog:               *slog,
+		RequestID:          *requestID,
+		Otel:               *otel,
+		Lax:                *lax,
+		Warn: func(warning string) {
+			log.Printf("warning: %s", warning)
+		},
+		Exclude: exclude,
+	}
+
+	// A directory or glob input generates one output per matching file,
+	// with -output used as the file name pattern
+	isDir := false
+	if info, err := os.Stat(*inputFile); err == nil && info.IsDir() {
+		isDir = true
+	}
+	if *split && (isDir || isGlob(*inputFile)) {
+		log.Fatalf("Error: -split cannot be used with a directory or glob input")
+	}
+	if *mocks && (isDir || isGlob(*inputFile)) {
+		log.Fatalf("Error: -mocks cannot be used with a directory or glob input")
+	}
+	if *watch && *check {
+		log.Fatalf("Error: -watch cannot be used with -check")
+	}
+	if *dump {
+		if *watch || *check || *lint || *lintStrict {
+			log.Fatalf("Error: -dump cannot be used with -watch, -check or -lint")
+		}
+		methods, err := dumpInputs(*inputFile, isDir, opts)
+		if err != nil {
+			log.Fatalf("Error parsing methods: %v", err)
+		}
+		os.Stdout.Write(methods)
+		return
+	}
+	if *lint || *lintStrict {
+		if *watch || *check {
+			log.Fatalf("Error: -lint cannot be used with -watch or -check")
+		}
+		warnings, err := lintInputs(*inputFile, isDir, opts)
+		exitOnWarnings(warnings, err, *lintStrict)
+		return
+	}
+	cache := generator.NewFileCache()
+	if isDir && *watch {
+		watchInputs(*inputFile, func() ([]string, error) {
+			return cache.GenerateDir(*inputFile, *outputFile, opts)
+		})
+		return
+	}
+	if isDir {
+		if *check {
+			exitOnDiff(generator.CheckDir(*inputFile, *outputFile, opts))
+			return
+		}
+		err := generator.GenerateDirWithOptions(*inputFile, *outputFile, opts)
+		if err != nil {
+			log.Fatalf("Error generating handlers: %v", err)
+		}
+		fmt.Printf("Generated handlers for %s\n", *inputFile)
+		return
+	}
+	if isGlob(*inputFile) && *watch {
+		watchInputs(*inputFile, func() ([]string, error) {
+			// Match again on every poll to pick up new files
+			inputFiles, err := filepath.Glob(*inputFile)
+			if err != nil {
+				return nil, err
+			}
+			return cache.GenerateFiles(inputFiles, *outputFile, opts)
+		})
+		return
+	}
+	if isGlob(*inputFile) {
+		inputFiles, err := filepath.Glob(*inputFile)
+		if err != nil {
+			log.Fatalf("Error matching input files: %v", err)
+		}
+		if *check {
+			exitOnDiff(generator.CheckFiles(inputFiles, *outputFile, opts))
+			return
+		}
+		err = generator.GenerateFiles(inputFiles, *outputFile, opts)
+		if err != nil {
+			log.Fatalf("Error generating handlers: %v", err)
+		}
+		fmt.Printf("Generated handlers for %s\n", *inputFile)
 		return
 	}
 
-	inputFile := os.Args[1]
-	outputFile := os.Args[2]
+	// A comma-separated list of files is merged into a single package output
+	inputFiles := strings.Split(*inputFile, ",")
 
-	err := generator.Generate(inputFile, outputFile)
+	// With -split, -output is the directory of the per-receiver files
+	if *split {
+		outputDir := *outputFile
+		if outputDir == "" {
+			outputDir = filepath.Dir(inputFiles[0])
+		}
+		if outputDir == generator.StdoutPath {
+			log.Fatalf("Error: -split cannot be used when writing to stdout")
+		}
+		if *check {
+			exitOnDiff(generator.CheckSplit(inputFiles, outputDir, opts))
+			return
+		}
+		if *mocks {
+			opts.MocksFile = generator.SplitMocksPath(outputDir, inputFiles[0])
+		}
+		if *watch {
+			watchInputs(*inputFile, func() ([]string, error) {
+				changed, err := cache.Changed(inputFiles)
+				if err != nil || !changed {
+					return nil, err
+				}
+				err = generator.GenerateSplit(inputFiles, outputDir, opts)
+				if err != nil {
+					return nil, err
+				}
+				return []string{outputDir}, nil
+			})
+			return
+		}
+		err := generator.GenerateSplit(inputFiles, outputDir, opts)
+		if err != nil {
+			log.Fatalf("Error generating handlers: %v", err)
+		}
+		fmt.Printf("Generated handlers written to %s\n", outputDir)
+		return
+	}
+
+	if *outputFile == "" {
+		if inputFiles[0] == generator.StdinPath {
+			*outputFile = generator.StdoutPath
+		} else {
+			*outputFile = generator.OutputPath(inputFiles[0], generator.DefaultOutPattern)
+		}
+	}
+
+	if *check {
+		if *outputFile == generator.StdoutPath {
+			log.Fatalf("Error: -check cannot be used when writing to stdout")
+		}
+		exitOnDiff(generator.CheckPackage(inputFiles, *outputFile, opts))
+		return
+	}
+
+	if *mocks {
+		if *outputFile == generator.StdoutPath {
+			log.Fatalf("Error: -mocks cannot be used when writing to stdout")
+		}
+		opts.MocksFile = generator.MocksPath(*outputFile)
+	}
+
+	if *watch {
+		if inputFiles[0] == generator.StdinPath || *outputFile == generator.StdoutPath {
+			log.Fatalf("Error: -watch cannot be used with stdin or stdout")
+		}
+		watchInputs(*inputFile, func() ([]string, error) {
+			changed, err := cache.Changed(inputFiles)
+			if err != nil || !changed {
+				return nil, err
+			}
+			written, err := generator.GeneratePackageChanged(inputFiles, *outputFile, opts)
+			if err != nil || !written {
+				return nil, err
+			}
+			return []string{*outputFile}, nil
+		})
+		return
+	}
+
+	written, err := generator.GeneratePackageChanged(inputFiles, *outputFile, opts)
 	if err != nil {
 		log.Fatalf("Error generating handlers: %v", err)
 	}
 
-	fmt.Printf("Generated handlers written to %s\n", outputFile)
+	if *outputFile == generator.StdoutPath {
+		return
+	}
+	if written {
+		fmt.Printf("Generated handlers written to %s\n", *outputFile)
+	} else {
+		fmt.Printf("Generated handlers in %s are up to date\n", *outputFile)
+	}
+}
+
+// exitOnDiff prints diff and exits with status 1 if the generated code
+// differs from the existing output files.
+func exitOnDiff(diff string, err error) {
+	if err != nil {
+		log.Fatalf("Error checking handlers: %v", err)
+	}
+	if diff != "" {
+		fmt.Print(diff)
+		os.Exit(1)
+	}
+}
+
+// lintInputs returns the lint warnings about input, a directory, glob or
+// comma-separated list of files of one package, parsed with opts.
+func lintInputs(input string, isDir bool, opts generator.Options) ([]string, error) {
+	if isDir {
+		return generator.LintDir(input, opts)
+	}
+	if isGlob(input) {
+		inputFiles, err := filepath.Glob(input)
+		if err != nil {
+			return nil, err
+		}
+		return generator.LintFiles(inputFiles, opts)
+	}
+	return generator.LintPackage(strings.Split(input, ","), opts)
+}
+
+// dumpInputs returns the methods parsed from input, a directory, glob or
+// comma-separated list of files of one package, as JSON, parsed with opts.
+func dumpInputs(input string, isDir bool, opts generator.Options) ([]byte, error) {
+	if isDir {
+		return generator.DumpDir(input, opts)
+	}
+	if isGlob(input) {
+		inputFiles, err := filepath.Glob(input)
+		if err != nil {
+			return nil, err
+		}
+		return generator.DumpFiles(inputFiles, opts)
+	}
+	return generator.DumpPackage(strings.Split(input, ","), opts)
+}
+
+// exitOnWarnings prints warnings to stderr, exiting with 1 if there are
+// any and strict is set.
+func exitOnWarnings(warnings []string, err error, strict bool) {
+	if err != nil {
+		log.Fatalf("Error linting annotations: %v", err)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	if strict && len(warnings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// watchInterval is the interval -watch polls the input files at.
+const watchInterval = 500 * time.Millisecond
+
+// watchInputs calls generate every watchInterval until interrupted and logs
+// the outputs it reports to have regenerated, or its error. generate is
+// expected to skip the work if no input changed since the last call.
+func watchInputs(input string, generate func() ([]string, error)) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Printf("Watching %s for changes", input)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		outputs, err := generate()
+		for _, output := range outputs {
+			log.Printf("Regenerated %s in %s", output, time.Since(start).Round(time.Millisecond))
+		}
+		if err != nil {
+			log.Printf("Error generating handlers: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stringsFlag is a flag.Value collecting the values of a repeatable flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// isGlob reports whether path contains any glob meta characters.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
 }
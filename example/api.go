@@ -2,9 +2,13 @@ package example
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ApiError represents an API error with an associated HTTP status code.
@@ -24,6 +28,9 @@ const (
 	statusAdmin     = 20
 )
 
+// reservedLogins are the logins users cannot sign up with, in lower case.
+var reservedLogins = []string{"administrator", "superuser"}
+
 // MyApi represents the main API structure.
 type MyApi struct {
 	statuses map[string]int
@@ -55,23 +62,23 @@ func NewMyApi() *MyApi {
 
 // ProfileParams represents the parameters for the Profile method.
 type ProfileParams struct {
-	Login string `apivalidator:"required"`
+	Login string `apivalidator:"required,trim"`
 }
 
 // CreateParams represents the parameters for the Create method.
 type CreateParams struct {
-	Login  string `apivalidator:"required,min=10"`
-	Name   string `apivalidator:"paramname=full_name"`
+	Login  string `apivalidator:"required,min=10,custom=ValidateLogin,code=INVALID_LOGIN"`
+	Name   string `apivalidator:"paramname=full_name,aliases=name|fullname"`
 	Status string `apivalidator:"enum=user|moderator|admin,default=user"`
 	Age    int    `apivalidator:"min=0,max=128"`
 }
 
 // User represents a user in the system.
 type User struct {
-	ID       uint64 `json:"id"`
-	Login    string `json:"login"`
-	FullName string `json:"full_name"`
-	Status   int    `json:"status"`
+	ID       uint64 `json:"id" xml:"id"`
+	Login    string `json:"login" xml:"login"`
+	FullName string `json:"full_name" xml:"full_name"`
+	Status   int    `json:"status" xml:"status"`
 }
 
 // NewUser represents a newly created user.
@@ -84,6 +91,9 @@ func (srv *MyApi) Profile(ctx context.Context, in ProfileParams) (*User, error)
 	if in.Login == "bad_user" {
 		return nil, fmt.Errorf("bad user")
 	}
+	if in.Login == "panic_user" {
+		panic("panic user")
+	}
 
 	srv.mu.RLock()
 	user, exist := srv.users[in.Login]
@@ -95,6 +105,15 @@ func (srv *MyApi) Profile(ctx context.Context, in ProfileParams) (*User, error)
 	return user, nil
 }
 
+// ValidateLogin rejects logins reserved for the staff. It is the custom
+// validator of the login of CreateParams.
+func (srv *MyApi) ValidateLogin(login string) error {
+	if slices.Contains(reservedLogins, strings.ToLower(login)) {
+		return fmt.Errorf("login %s is reserved", login)
+	}
+	return nil
+}
+
 // apigen:api {"url": "/user/create", "auth": true, "method": "POST", "auth_env_key": "MY_API_KEY"}
 func (srv *MyApi) Create(ctx context.Context, in CreateParams) (*NewUser, error) {
 	if in.Login == "bad_username" {
@@ -121,6 +140,40 @@ func (srv *MyApi) Create(ctx context.Context, in CreateParams) (*NewUser, error)
 	return &NewUser{id}, nil
 }
 
+// UserParams represents the parameters for the User method.
+type UserParams struct {
+	Login string `apivalidator:"required"`
+}
+
+// apigen:api {"url": "/user/{login}", "method": "GET"}
+func (srv *MyApi) User(ctx context.Context, in UserParams) (*User, error) {
+	return srv.Profile(ctx, ProfileParams{Login: in.Login})
+}
+
+// WhoamiParams represents the parameters for the Whoami method.
+type WhoamiParams struct {
+	Login string `apivalidator:"required"`
+}
+
+// Client describes a client as the server sees it.
+type Client struct {
+	Login     string `json:"login"`
+	Addr      string `json:"addr"`
+	UserAgent string `json:"user_agent"`
+}
+
+// Whoami takes the request as a third parameter to read the address and
+// headers of the client.
+//
+// apigen:api {"url": "/user/whoami", "method": "GET"}
+func (srv *MyApi) Whoami(ctx context.Context, in WhoamiParams, r *http.Request) (*Client, error) {
+	return &Client{
+		Login:     in.Login,
+		Addr:      r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+	}, nil
+}
+
 // OtherApi represents another API structure for demonstration purposes.
 type OtherApi struct{}
 
@@ -154,3 +207,188 @@ func (srv *OtherApi) Create(ctx context.Context, in OtherCreateParams) (*OtherUs
 		Level:    in.Level,
 	}, nil
 }
+
+// ErrProductNotFound is returned for products that do not exist.
+var ErrProductNotFound = errors.New("product not found")
+
+func init() {
+	ErrorStatuses = append(ErrorStatuses, ErrorStatus{Err: ErrProductNotFound, Status: http.StatusNotFound})
+}
+
+// ProductApi represents an API structure showcasing field validators.
+type ProductApi struct{}
+
+// NewProductApi creates a new ProductApi instance.
+func NewProductApi() *ProductApi {
+	return &ProductApi{}
+}
+
+// ProductCreateParams represents the parameters for the ProductApi's Create method.
+type ProductCreateParams struct {
+	Sku    string  `apivalidator:"required,regex=^[A-Z]{3}-\\d+$"`
+	Code   string  `apivalidator:"regex=^[a-z]{2\\,4}$,msg=code must be 2 to 4 \"lowercase\" letters, e.g. abc"`
+	Owner  string  `apivalidator:"required,email"`
+	Title  string  `apivalidator:"min=3,max=8"`
+	Stock  int     `apivalidator:"min=3,max=8"`
+	Active bool    `apivalidator:"default=true"`
+	Price  float64 `apivalidator:"min_ex=0,max=9999.99"`
+}
+
+// Product represents a product in the ProductApi system.
+type Product struct {
+	Sku       string  `json:"sku"`
+	Code      string  `json:"code"`
+	Owner     string  `json:"owner"`
+	Title     string  `json:"title"`
+	Stock     int     `json:"stock"`
+	Active    bool    `json:"active"`
+	Price     float64 `json:"price"`
+	Warehouse uint64  `json:"warehouse,omitempty"`
+}
+
+// apigen:api {"url": "/product/create", "method": "POST", "error_code": "INVALID_PRODUCT", "success_status": 201}
+func (srv *ProductApi) Create(ctx context.Context, in ProductCreateParams) (*Product, error) {
+	return &Product{
+		Sku:    in.Sku,
+		Code:   in.Code,
+		Owner:  in.Owner,
+		Title:  in.Title,
+		Stock:  in.Stock,
+		Active: in.Active,
+		Price:  in.Price,
+	}, nil
+}
+
+// ProductUpdateParams represents the parameters for the ProductApi's Update method.
+type ProductUpdateParams struct {
+	Sku      string `apivalidator:"required,regex=^[A-Z]{3}-\\d+$"`
+	Stock    int    `apivalidator:"min=0"`
+	OnSale   bool   `apivalidator:"paramname=on_sale"`
+	Discount string `apivalidator:"paramname=discount_code,required_if=on_sale=true"`
+	Revision string `apivalidator:"uuid"`
+}
+
+// apigen:api {"url": "/product/update", "method": "PUT"}
+func (srv *ProductApi) Update(ctx context.Context, in ProductUpdateParams) (*Product, error) {
+	return &Product{
+		Sku:   in.Sku,
+		Stock: in.Stock,
+	}, nil
+}
+
+// ProductDeleteParams represents the parameters for the ProductApi's Delete method.
+type ProductDeleteParams struct {
+	Sku string `apivalidator:"required"`
+}
+
+// apigen:api {"url": "/product/delete", "method": "DELETE", "auth": true, "auth_env_key": "MY_API_KEY", "auth_header": "X-Api-Key"}
+func (srv *ProductApi) Delete(ctx context.Context, in ProductDeleteParams) (*Product, error) {
+	return &Product{
+		Sku: in.Sku,
+	}, nil
+}
+
+// ProductArchiveParams represents the parameters for the ProductApi's Archive method.
+type ProductArchiveParams struct {
+	Sku string `apivalidator:"required"`
+}
+
+// apigen:api {"url": "/product/archive", "method": "POST", "auth": true, "auth_env_key": "MY_API_KEY", "auth_scheme": "bearer"}
+func (srv *ProductApi) Archive(ctx context.Context, in ProductArchiveParams) (*Product, error) {
+	if in.Sku == "ABC-000" {
+		return nil, fmt.Errorf("archive %s: %w", in.Sku, ErrProductNotFound)
+	}
+	return &Product{
+		Sku: in.Sku,
+	}, nil
+}
+
+// ProductStockParams represents the parameters for the ProductApi's Stock method.
+type ProductStockParams struct {
+	Sku       string `apivalidator:"required"`
+	Delay     int32  `apivalidator:"min=0,max_ex=1000"`
+	Warehouse uint64
+}
+
+// Stock looks up the stock of a product in a warehouse that answers after
+// Delay milliseconds.
+//
+// apigen:api {"url": "/product/stock", "method": "GET", "timeout_ms": 100}
+func (srv *ProductApi) Stock(ctx context.Context, in ProductStockParams) (*Product, error) {
+	select {
+	case <-time.After(time.Duration(in.Delay) * time.Millisecond):
+		return &Product{
+			Sku:       in.Sku,
+			Stock:     42,
+			Warehouse: in.Warehouse,
+		}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ProductReviewParams represents the parameters for the ProductApi's Review method.
+type ProductReviewParams struct {
+	Sku    string `apivalidator:"required"`
+	Rating int    `apivalidator:"min=1,max=5"`
+	Text   string `apivalidator:"max=140"`
+}
+
+// ProductReview represents a review of a product.
+type ProductReview struct {
+	Sku    string `json:"sku"`
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// Review reports all invalid parameters at once, so forms can show them together.
+//
+// apigen:api {"url": "/product/review", "method": "POST", "collect_errors": true, "max_body_bytes": 1024}
+func (srv *ProductApi) Review(ctx context.Context, in ProductReviewParams) (*ProductReview, error) {
+	return &ProductReview{
+		Sku:    in.Sku,
+		Rating: in.Rating,
+		Text:   in.Text,
+	}, nil
+}
+
+// Pagination represents the paging parameters shared by list methods.
+type Pagination struct {
+	Limit  int `apivalidator:"min=1,max=100,multiple_of=10,default=20"`
+	Offset int `apivalidator:"min=0"`
+}
+
+// ProductListParams represents the parameters for the ProductApi's List method.
+type ProductListParams struct {
+	Pagination
+	Owner  string    `apivalidator:"required,email"`
+	Sort   string    `apivalidator:"enum_ci=name|price,default=name"`
+	Status int       `apivalidator:"enum=0|1|2"`
+	Since  time.Time `apivalidator:"datetime=2006-01-02"`
+	Until  string    `apivalidator:"datetime=2006-01-02"`
+}
+
+// ProductList represents a page of products.
+type ProductList struct {
+	Owner  string `json:"owner"`
+	Sort   string `json:"sort"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Since  string `json:"since,omitempty"`
+	Until  string `json:"until,omitempty"`
+}
+
+// apigen:api {"url": "/product/list", "method": "GET"}
+func (srv *ProductApi) List(ctx context.Context, in ProductListParams) (*ProductList, error) {
+	list := &ProductList{
+		Owner:  in.Owner,
+		Sort:   in.Sort,
+		Limit:  in.Limit,
+		Offset: in.Offset,
+		Until:  in.Until,
+	}
+	if !in.Since.IsZero() {
+		list.Since = in.Since.Format(time.DateOnly)
+	}
+	return list, nil
+}
@@ -1,103 +1,484 @@
-// Code generated by gonerator. DO NOT EDIT.
+// Code generated by gonerator from api.go; DO NOT EDIT.
 
 package example
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"net/mail"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-func (h *MyApi) handlerProfile(w http.ResponseWriter, r *http.Request) {
+// URLs of the API methods.
+const (
+	URLMyApiProfile      = "/user/profile"
+	URLMyApiCreate       = "/user/create"
+	URLMyApiUser         = "/user/{login}"
+	URLMyApiWhoami       = "/user/whoami"
+	URLOtherApiCreate    = "/user/create"
+	URLProductApiCreate  = "/product/create"
+	URLProductApiUpdate  = "/product/update"
+	URLProductApiDelete  = "/product/delete"
+	URLProductApiArchive = "/product/archive"
+	URLProductApiStock   = "/product/stock"
+	URLProductApiReview  = "/product/review"
+	URLProductApiList    = "/product/list"
+)
 
-	allowedMethods := strings.Split("GET,POST", ",")
-	methodAllowed := false
-	for _, m := range allowedMethods {
-		if r.Method == strings.TrimSpace(m) {
-			methodAllowed = true
-			break
+// RouteInfo describes the route of an API method.
+type RouteInfo struct {
+	// Name is the name of the API method.
+	Name string
+	// Methods are the HTTP methods the route accepts.
+	Methods []string
+	// Auth reports whether requests must be authenticated.
+	Auth bool
+	// Params are the names of the parameters of the method.
+	Params []string
+}
+
+// ErrorStatus maps the errors matching Err, as reported by errors.Is, to
+// the status of the response.
+type ErrorStatus struct {
+	Err    error
+	Status int
+}
+
+// ErrorStatuses maps errors returned by API methods to the status of the
+// response. An error is answered with the status of the first entry it
+// matches, so an error wrapping several of them always gets the same
+// status. Append entries in an init function of the package.
+var ErrorStatuses = []ErrorStatus{
+	{Err: context.DeadlineExceeded, Status: http.StatusGatewayTimeout},
+}
+
+// errorStatus returns the response status for an error returned by an API
+// method: the status of an ApiError, the status of the first entry of
+// ErrorStatuses err matches, or 500 Internal Server Error.
+func errorStatus(err error) int {
+	var apiErr ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatus
+	}
+	for _, entry := range ErrorStatuses {
+		if errors.Is(err, entry.Err) {
+			return entry.Status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// responseEnvelope is the body of successful responses. Encoding a struct
+// rather than a map saves allocating the map on every request.
+type responseEnvelope struct {
+	Error    string      "json:\"error\""
+	Response interface{} "json:\"response\""
+}
+
+// maxPooledBufferSize is the capacity above which encoding buffers are
+// dropped rather than returned to jsonBufferPool, so a single large
+// response does not keep its memory alive.
+const maxPooledBufferSize = 64 << 10
+
+// jsonBuffer is a buffer responses are encoded into along with an
+// encoder writing to it, so neither is allocated per request.
+type jsonBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+// jsonBufferPool holds the *jsonBuffer values of writeJSON.
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := &jsonBuffer{}
+		buf.enc = json.NewEncoder(&buf.Buffer)
+		return buf
+	},
+}
+
+// writeJSON writes v encoded as JSON with status. The response is encoded
+// into a pooled buffer first, so an encoding error is answered with 500
+// instead of a truncated body.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	buf := jsonBufferPool.Get().(*jsonBuffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			jsonBufferPool.Put(buf)
+		}
+	}()
+
+	err := buf.enc.Encode(v)
+	if err != nil {
+		http.Error(w, "{\"error\": \"cannot encode response\"}", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// writeError answers with an error body holding msg and, unless it is
+// empty, the error code: as XML if r prefers it and as JSON
+// otherwise. Like http.Error, the JSON body is sent as text/plain.
+func writeError(w http.ResponseWriter, r *http.Request, status int, msg, code string) {
+	if acceptsXML(r) {
+		writeXML(w, status, xmlEnvelope{Error: msg, Code: code})
+		return
+	}
+	body := "{\"error\": " + jsonQuote(msg)
+	if code != "" {
+		body += ", \"code\": " + jsonQuote(code)
+	}
+	http.Error(w, body+"}", status)
+}
+
+// jsonQuote returns s encoded as a JSON string without HTML escaping.
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// xmlEnvelope is the root element of XML responses.
+type xmlEnvelope struct {
+	XMLName  xml.Name    "xml:\"envelope\""
+	Error    string      "xml:\"error,omitempty\""
+	Code     string      "xml:\"code,omitempty\""
+	Errors   []string    "xml:\"errors,omitempty\""
+	Response interface{} "xml:\"response,omitempty\""
+}
+
+// acceptsXML reports whether the Accept header of r prefers XML to JSON:
+// the q-value of application/xml or text/xml is higher than the q-value of
+// the most specific media range matching application/json. JSON is kept
+// on a tie.
+func acceptsXML(r *http.Request) bool {
+	var xmlQ, jsonQ float64
+	jsonSpecificity := 0
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				q, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		specificity := 0
+		switch strings.TrimSpace(mediaType) {
+		case "application/xml", "text/xml":
+			if q > xmlQ {
+				xmlQ = q
+			}
+		case "application/json":
+			specificity = 3
+		case "application/*":
+			specificity = 2
+		case "*/*":
+			specificity = 1
+		}
+		if specificity > jsonSpecificity {
+			jsonQ, jsonSpecificity = q, specificity
+		}
+	}
+	return xmlQ > jsonQ
+}
+
+// writeXML writes v as an XML response with status.
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
+
+// GzipMinSize is the size in bytes of the smallest response body that is
+// compressed. Smaller responses are written uncompressed.
+var GzipMinSize = 1024
+
+// acceptsGzip reports whether the Accept-Encoding header of r accepts gzip.
+func acceptsGzip(r *http.Request) bool {
+	encodings := r.Header.Get("Accept-Encoding")
+	for encodings != "" {
+		var encoding string
+		encoding, encodings, _ = strings.Cut(encodings, ",")
+		name, params, _ := strings.Cut(encoding, ";")
+		if strings.TrimSpace(name) == "gzip" {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// gzipWriter compresses the response written to the wrapped ResponseWriter
+// with gzip once its body reaches GzipMinSize bytes. Until then the body
+// and status are buffered. Close must be called to write the rest.
+type gzipWriter struct {
+	http.ResponseWriter
+	status int
+	buf    []byte
+	gz     *gzip.Writer
+}
+
+func (w *gzipWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= GzipMinSize {
+		err := w.compress()
+		if err != nil {
+			return 0, err
 		}
 	}
-	if !methodAllowed {
-		http.Error(w, "{\"error\": \"bad method\"}", http.StatusNotAcceptable)
+	return len(p), nil
+}
+
+// compress starts compressing the response, beginning with the buffered body.
+func (w *gzipWriter) compress() error {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Encoding", "gzip")
+	w.writeHeader()
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// writeHeader writes the buffered status, if any, to the wrapped ResponseWriter.
+func (w *gzipWriter) writeHeader() {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// Flush compresses the buffered body and sends all data written so far to the client.
+func (w *gzipWriter) Flush() {
+	if w.gz == nil && w.compress() != nil {
+		return
+	}
+	if w.gz.Flush() != nil {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes the rest of the response, uncompressed if it is smaller than GzipMinSize.
+func (w *gzipWriter) Close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	w.writeHeader()
+	_, err := w.ResponseWriter.Write(w.buf)
+	return err
+}
+
+// MyApiRoutes describes the routes of MyApi by URL.
+var MyApiRoutes = map[string]RouteInfo{
+	URLMyApiProfile: {
+		Name:    "Profile",
+		Methods: []string{"GET", "POST"},
+		Auth:    false,
+		Params:  []string{"login"},
+	},
+	URLMyApiCreate: {
+		Name:    "Create",
+		Methods: []string{"POST"},
+		Auth:    true,
+		Params:  []string{"login", "full_name", "status", "age"},
+	},
+	URLMyApiUser: {
+		Name:    "User",
+		Methods: []string{"GET"},
+		Auth:    false,
+		Params:  []string{"login"},
+	},
+	URLMyApiWhoami: {
+		Name:    "Whoami",
+		Methods: []string{"GET"},
+		Auth:    false,
+		Params:  []string{"login"},
+	},
+}
+
+func (h *MyApi) handlerProfile(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET", "POST":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
 		return
 	}
 
 	var params ProfileParams
 
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
 	var queryParams url.Values
 	if r.Method == "GET" {
 		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
 	} else {
-		err := r.ParseForm()
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
 		if err != nil {
-			http.Error(w, "{\"error\": \""+err.Error()+"\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
 			return
 		}
 		queryParams = r.Form
 	}
 
-	params.Login = queryParams.Get("login")
+	params.Login = strings.TrimSpace(queryParams.Get("login"))
 
 	if params.Login == "" {
-		http.Error(w, "{\"error\": \"login must be not empty\"}", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "login must be not empty", "VALIDATION_ERROR")
 		return
 	}
 
 	res, err := h.Profile(r.Context(), params)
+
 	if err != nil {
-		if apiErr, ok := err.(ApiError); ok {
-			http.Error(w, "{\"error\": \""+apiErr.Error()+"\"}", apiErr.HTTPStatus)
-		} else {
-			http.Error(w, "{\"error\": \""+err.Error()+"\"}", http.StatusInternalServerError)
-		}
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
 		return
 	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error":    "",
-		"response": res,
-	})
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
 }
 
+var enumMyApiCreateStatus = map[string]bool{"user": true, "moderator": true, "admin": true}
+
 func (h *MyApi) handlerCreate(w http.ResponseWriter, r *http.Request) {
 
 	authKey := os.Getenv("MY_API_KEY")
 	if authKey == "" {
-		http.Error(w, "{\"error\": \"Server configuration error: missing auth key\"}", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Server configuration error: missing auth key", "")
 		return
 	}
-	if r.Header.Get("X-Auth") != authKey {
-		http.Error(w, "{\"error\": \"unauthorized\"}", http.StatusForbidden)
+
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Auth")), []byte(authKey)) != 1 {
+		writeError(w, r, http.StatusForbidden, "unauthorized", "")
 		return
 	}
 
-	allowedMethods := strings.Split("POST", ",")
-	methodAllowed := false
-	for _, m := range allowedMethods {
-		if r.Method == strings.TrimSpace(m) {
-			methodAllowed = true
-			break
-		}
-	}
-	if !methodAllowed {
-		http.Error(w, "{\"error\": \"bad method\"}", http.StatusNotAcceptable)
+	switch r.Method {
+	case "POST":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
 		return
 	}
 
 	var params CreateParams
 
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
 	var queryParams url.Values
 	if r.Method == "GET" {
 		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
 	} else {
-		err := r.ParseForm()
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
 		if err != nil {
-			http.Error(w, "{\"error\": \""+err.Error()+"\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
 			return
 		}
 		queryParams = r.Form
@@ -106,76 +487,320 @@ func (h *MyApi) handlerCreate(w http.ResponseWriter, r *http.Request) {
 	params.Login = queryParams.Get("login")
 
 	if params.Login == "" {
-		http.Error(w, "{\"error\": \"login must be not empty\"}", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "login must be not empty", "INVALID_LOGIN")
 		return
 	}
 
 	if len(params.Login) < 10 {
-		http.Error(w, "{\"error\": \"login len must be >= 10\"}", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "login len must be >= 10", "INVALID_LOGIN")
 		return
 	}
 
+	if err := h.ValidateLogin(params.Login); err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error(), "INVALID_LOGIN")
+		return
+	}
+
+	if !queryParams.Has("full_name") {
+		switch {
+		case queryParams.Has("name"):
+			queryParams["full_name"] = queryParams["name"]
+		case queryParams.Has("fullname"):
+			queryParams["full_name"] = queryParams["fullname"]
+		}
+	}
+
 	params.Name = queryParams.Get("full_name")
 
 	params.Status = queryParams.Get("status")
 
-	validValues := []string{"user", "moderator", "admin"}
-	isValid := false
-	for _, v := range validValues {
-		if params.Status == v {
-			isValid = true
-			break
-		}
-	}
-	if !isValid && params.Status != "" {
-		http.Error(w, "{\"error\": \"status must be one of ["+strings.Join(validValues, ", ")+"]\"}", http.StatusBadRequest)
+	if !enumMyApiCreateStatus[params.Status] && params.Status != "" {
+		writeError(w, r, http.StatusBadRequest, "status must be one of [user, moderator, admin]", "VALIDATION_ERROR")
 		return
 	}
 
-	if params.Status == "" {
+	if !queryParams.Has("status") {
 		params.Status = "user"
 	}
 
 	AgeStr := queryParams.Get("age")
+
 	if AgeStr != "" {
-		AgeVal, err := strconv.Atoi(AgeStr)
+		AgeVal, err := strconv.ParseInt(AgeStr, 10, 0)
 		if err != nil {
-			http.Error(w, "{\"error\": \"age must be int\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "age must be int", "VALIDATION_ERROR")
 			return
 		}
 
 		if AgeVal < 0 {
-			http.Error(w, "{\"error\": \"age must be >= 0\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "age must be >= 0", "VALIDATION_ERROR")
 			return
 		}
 
 		if AgeVal > 128 {
-			http.Error(w, "{\"error\": \"age must be <= 128\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "age must be <= 128", "VALIDATION_ERROR")
 			return
 		}
 
-		params.Age = AgeVal
+		params.Age = int(AgeVal)
 	}
 
 	res, err := h.Create(r.Context(), params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+func (h *MyApi) handlerUser(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params UserParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	pathSegments := strings.Split(r.URL.Path, "/")
+	queryParams.Set("login", pathSegments[2])
+
+	params.Login = queryParams.Get("login")
+
+	if params.Login == "" {
+		writeError(w, r, http.StatusBadRequest, "login must be not empty", "VALIDATION_ERROR")
+		return
+	}
+
+	res, err := h.User(r.Context(), params)
+
 	if err != nil {
-		if apiErr, ok := err.(ApiError); ok {
-			http.Error(w, "{\"error\": \""+apiErr.Error()+"\"}", apiErr.HTTPStatus)
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+func (h *MyApi) handlerWhoami(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params WhoamiParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
 		} else {
-			http.Error(w, "{\"error\": \""+err.Error()+"\"}", http.StatusInternalServerError)
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
 		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	params.Login = queryParams.Get("login")
+
+	if params.Login == "" {
+		writeError(w, r, http.StatusBadRequest, "login must be not empty", "VALIDATION_ERROR")
+		return
+	}
+
+	res, err := h.Whoami(r.Context(), params, r)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
 		return
 	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+// introspectionMyApi describes the API methods of MyApi.
+const introspectionMyApi = "{\"methods\":[{\"name\":\"Profile\",\"url\":\"/user/profile\",\"http_methods\":[\"GET\",\"POST\"],\"auth\":false,\"params\":[{\"name\":\"login\",\"type\":\"string\",\"required\":true}]},{\"name\":\"Create\",\"url\":\"/user/create\",\"http_methods\":[\"POST\"],\"auth\":true,\"params\":[{\"name\":\"login\",\"type\":\"string\",\"required\":true,\"min\":10},{\"name\":\"full_name\",\"aliases\":[\"name\",\"fullname\"],\"type\":\"string\"},{\"name\":\"status\",\"type\":\"string\",\"enum\":[\"user\",\"moderator\",\"admin\"],\"default\":\"user\"},{\"name\":\"age\",\"type\":\"int\",\"min\":0,\"max\":128}]},{\"name\":\"User\",\"url\":\"/user/{login}\",\"http_methods\":[\"GET\"],\"auth\":false,\"params\":[{\"name\":\"login\",\"type\":\"string\",\"required\":true}]},{\"name\":\"Whoami\",\"url\":\"/user/whoami\",\"http_methods\":[\"GET\"],\"auth\":false,\"params\":[{\"name\":\"login\",\"type\":\"string\",\"required\":true}]}]}\n"
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error":    "",
-		"response": res,
-	})
+// RegisterMyApiRoutes registers srv on mux for the URL of every API method
+// of MyApi, so it can be served alongside other routes.
+// Routes shared by all receivers are only registered if mux does not serve them yet.
+func RegisterMyApiRoutes(mux *http.ServeMux, srv *MyApi) {
+	mux.Handle("/user/profile", srv)
+	mux.Handle("/user/create", srv)
+	mux.Handle("/user/{login}", srv)
+	mux.Handle("/user/whoami", srv)
+	if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "/_introspect"}}); pattern == "" {
+		mux.Handle("/_introspect", srv)
+	}
+	if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "/healthz"}}); pattern == "" {
+		mux.Handle("/healthz", srv)
+	}
 }
 
 func (h *MyApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if acceptsGzip(r) {
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.Close()
+		w = gw
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			log.Printf("panic serving %s: %v", r.URL.Path, err)
+			writeError(w, r, http.StatusInternalServerError, "internal server error", "")
+		}
+	}()
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		segments := strings.Split(r.URL.Path, "/")
+		switch {
+		case r.URL.Path == "/user/profile":
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/user/create":
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Auth")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/user/whoami":
+			w.Header().Set("Access-Control-Allow-Methods", "GET")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case len(segments) == 3 && segments[0] == "" && segments[1] == "user" && segments[2] != "":
+			w.Header().Set("Access-Control-Allow-Methods", "GET")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
 	switch r.URL.Path {
+	case "/_introspect":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(introspectionMyApi))
+	case "/healthz":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"status\":\"ok\"}"))
 
 	case "/user/profile":
 		h.handlerProfile(w, r)
@@ -183,45 +808,101 @@ func (h *MyApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case "/user/create":
 		h.handlerCreate(w, r)
 
+	case "/user/whoami":
+		h.handlerWhoami(w, r)
+
 	default:
-		http.Error(w, "{\"error\": \"unknown method\"}", http.StatusNotFound)
+		// URLs with parameters are matched segment by segment
+		segments := strings.Split(r.URL.Path, "/")
+		if len(segments) == 3 && segments[0] == "" && segments[1] == "user" && segments[2] != "" {
+			h.handlerUser(w, r)
+			return
+		}
+		writeError(w, r, http.StatusNotFound, "unknown method", "")
 	}
 }
 
+// OtherApiRoutes describes the routes of OtherApi by URL.
+var OtherApiRoutes = map[string]RouteInfo{
+	URLOtherApiCreate: {
+		Name:    "Create",
+		Methods: []string{"POST"},
+		Auth:    true,
+		Params:  []string{"username", "account_name", "class", "level"},
+	},
+}
+
+var enumOtherApiCreateClass = map[string]bool{"warrior": true, "sorcerer": true, "rouge": true}
+
 func (h *OtherApi) handlerCreate(w http.ResponseWriter, r *http.Request) {
 
 	authKey := os.Getenv("OTHER_API_KEY")
 	if authKey == "" {
-		http.Error(w, "{\"error\": \"Server configuration error: missing auth key\"}", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Server configuration error: missing auth key", "")
 		return
 	}
-	if r.Header.Get("X-Auth") != authKey {
-		http.Error(w, "{\"error\": \"unauthorized\"}", http.StatusForbidden)
+
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Auth")), []byte(authKey)) != 1 {
+		writeError(w, r, http.StatusForbidden, "unauthorized", "")
 		return
 	}
 
-	allowedMethods := strings.Split("POST", ",")
-	methodAllowed := false
-	for _, m := range allowedMethods {
-		if r.Method == strings.TrimSpace(m) {
-			methodAllowed = true
-			break
-		}
-	}
-	if !methodAllowed {
-		http.Error(w, "{\"error\": \"bad method\"}", http.StatusNotAcceptable)
+	switch r.Method {
+	case "POST":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
 		return
 	}
 
 	var params OtherCreateParams
 
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
 	var queryParams url.Values
 	if r.Method == "GET" {
 		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
 	} else {
-		err := r.ParseForm()
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
 		if err != nil {
-			http.Error(w, "{\"error\": \""+err.Error()+"\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
 			return
 		}
 		queryParams = r.Form
@@ -230,12 +911,12 @@ func (h *OtherApi) handlerCreate(w http.ResponseWriter, r *http.Request) {
 	params.Username = queryParams.Get("username")
 
 	if params.Username == "" {
-		http.Error(w, "{\"error\": \"username must be not empty\"}", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "username must be not empty", "VALIDATION_ERROR")
 		return
 	}
 
 	if len(params.Username) < 3 {
-		http.Error(w, "{\"error\": \"username len must be >= 3\"}", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "username len must be >= 3", "VALIDATION_ERROR")
 		return
 	}
 
@@ -243,68 +924,1273 @@ func (h *OtherApi) handlerCreate(w http.ResponseWriter, r *http.Request) {
 
 	params.Class = queryParams.Get("class")
 
-	validValues := []string{"warrior", "sorcerer", "rouge"}
-	isValid := false
-	for _, v := range validValues {
-		if params.Class == v {
-			isValid = true
-			break
-		}
-	}
-	if !isValid && params.Class != "" {
-		http.Error(w, "{\"error\": \"class must be one of ["+strings.Join(validValues, ", ")+"]\"}", http.StatusBadRequest)
+	if !enumOtherApiCreateClass[params.Class] && params.Class != "" {
+		writeError(w, r, http.StatusBadRequest, "class must be one of [warrior, sorcerer, rouge]", "VALIDATION_ERROR")
 		return
 	}
 
-	if params.Class == "" {
+	if !queryParams.Has("class") {
 		params.Class = "warrior"
 	}
 
 	LevelStr := queryParams.Get("level")
+
 	if LevelStr != "" {
-		LevelVal, err := strconv.Atoi(LevelStr)
+		LevelVal, err := strconv.ParseInt(LevelStr, 10, 0)
 		if err != nil {
-			http.Error(w, "{\"error\": \"level must be int\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "level must be int", "VALIDATION_ERROR")
 			return
 		}
 
 		if LevelVal < 1 {
-			http.Error(w, "{\"error\": \"level must be >= 1\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "level must be >= 1", "VALIDATION_ERROR")
 			return
 		}
 
 		if LevelVal > 50 {
-			http.Error(w, "{\"error\": \"level must be <= 50\"}", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "level must be <= 50", "VALIDATION_ERROR")
 			return
 		}
 
-		params.Level = LevelVal
+		params.Level = int(LevelVal)
 	}
 
 	res, err := h.Create(r.Context(), params)
+
 	if err != nil {
-		if apiErr, ok := err.(ApiError); ok {
-			http.Error(w, "{\"error\": \""+apiErr.Error()+"\"}", apiErr.HTTPStatus)
-		} else {
-			http.Error(w, "{\"error\": \""+err.Error()+"\"}", http.StatusInternalServerError)
-		}
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
 		return
 	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error":    "",
-		"response": res,
-	})
+// introspectionOtherApi describes the API methods of OtherApi.
+const introspectionOtherApi = "{\"methods\":[{\"name\":\"Create\",\"url\":\"/user/create\",\"http_methods\":[\"POST\"],\"auth\":true,\"params\":[{\"name\":\"username\",\"type\":\"string\",\"required\":true,\"min\":3},{\"name\":\"account_name\",\"type\":\"string\"},{\"name\":\"class\",\"type\":\"string\",\"enum\":[\"warrior\",\"sorcerer\",\"rouge\"],\"default\":\"warrior\"},{\"name\":\"level\",\"type\":\"int\",\"min\":1,\"max\":50}]}]}\n"
+
+// RegisterOtherApiRoutes registers srv on mux for the URL of every API method
+// of OtherApi, so it can be served alongside other routes.
+// Routes shared by all receivers are only registered if mux does not serve them yet.
+func RegisterOtherApiRoutes(mux *http.ServeMux, srv *OtherApi) {
+	mux.Handle("/user/create", srv)
+	if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "/_introspect"}}); pattern == "" {
+		mux.Handle("/_introspect", srv)
+	}
+	if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "/healthz"}}); pattern == "" {
+		mux.Handle("/healthz", srv)
+	}
 }
 
 func (h *OtherApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if acceptsGzip(r) {
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.Close()
+		w = gw
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			log.Printf("panic serving %s: %v", r.URL.Path, err)
+			writeError(w, r, http.StatusInternalServerError, "internal server error", "")
+		}
+	}()
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		switch {
+		case r.URL.Path == "/user/create":
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Auth")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
 	switch r.URL.Path {
+	case "/_introspect":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(introspectionOtherApi))
+	case "/healthz":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"status\":\"ok\"}"))
 
 	case "/user/create":
 		h.handlerCreate(w, r)
 
 	default:
-		http.Error(w, "{\"error\": \"unknown method\"}", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "unknown method", "")
+	}
+}
+
+// ProductApiRoutes describes the routes of ProductApi by URL.
+var ProductApiRoutes = map[string]RouteInfo{
+	URLProductApiCreate: {
+		Name:    "Create",
+		Methods: []string{"POST"},
+		Auth:    false,
+		Params:  []string{"sku", "code", "owner", "title", "stock", "active", "price"},
+	},
+	URLProductApiUpdate: {
+		Name:    "Update",
+		Methods: []string{"PUT"},
+		Auth:    false,
+		Params:  []string{"sku", "stock", "on_sale", "discount_code", "revision"},
+	},
+	URLProductApiDelete: {
+		Name:    "Delete",
+		Methods: []string{"DELETE"},
+		Auth:    true,
+		Params:  []string{"sku"},
+	},
+	URLProductApiArchive: {
+		Name:    "Archive",
+		Methods: []string{"POST"},
+		Auth:    true,
+		Params:  []string{"sku"},
+	},
+	URLProductApiStock: {
+		Name:    "Stock",
+		Methods: []string{"GET"},
+		Auth:    false,
+		Params:  []string{"sku", "delay", "warehouse"},
+	},
+	URLProductApiReview: {
+		Name:    "Review",
+		Methods: []string{"POST"},
+		Auth:    false,
+		Params:  []string{"sku", "rating", "text"},
+	},
+	URLProductApiList: {
+		Name:    "List",
+		Methods: []string{"GET"},
+		Auth:    false,
+		Params:  []string{"limit", "offset", "owner", "sort", "status", "since", "until"},
+	},
+}
+
+var regexProductApiCreateSku = regexp.MustCompile("^[A-Z]{3}-\\d+$")
+
+var regexProductApiCreateCode = regexp.MustCompile("^[a-z]{2,4}$")
+
+func (h *ProductApi) handlerCreate(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params ProductCreateParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	params.Sku = queryParams.Get("sku")
+
+	if params.Sku == "" {
+		writeError(w, r, http.StatusBadRequest, "sku must be not empty", "INVALID_PRODUCT")
+		return
+	}
+
+	if params.Sku != "" && !regexProductApiCreateSku.MatchString(params.Sku) {
+		writeError(w, r, http.StatusBadRequest, "sku must match pattern ^[A-Z]{3}-\\d+$", "INVALID_PRODUCT")
+		return
+	}
+
+	params.Code = queryParams.Get("code")
+
+	if params.Code != "" && !regexProductApiCreateCode.MatchString(params.Code) {
+		writeError(w, r, http.StatusBadRequest, "code must be 2 to 4 \"lowercase\" letters, e.g. abc", "INVALID_PRODUCT")
+		return
+	}
+
+	params.Owner = queryParams.Get("owner")
+
+	if params.Owner == "" {
+		writeError(w, r, http.StatusBadRequest, "owner must be not empty", "INVALID_PRODUCT")
+		return
+	}
+
+	if params.Owner != "" {
+		if _, err := mail.ParseAddress(params.Owner); err != nil {
+			writeError(w, r, http.StatusBadRequest, "owner must be a valid email", "INVALID_PRODUCT")
+			return
+		}
+	}
+
+	params.Title = queryParams.Get("title")
+
+	if len(params.Title) < 3 {
+		writeError(w, r, http.StatusBadRequest, "title len must be >= 3", "INVALID_PRODUCT")
+		return
+	}
+
+	if len(params.Title) > 8 {
+		writeError(w, r, http.StatusBadRequest, "title len must be <= 8", "INVALID_PRODUCT")
+		return
+	}
+
+	StockStr := queryParams.Get("stock")
+
+	if StockStr != "" {
+		StockVal, err := strconv.ParseInt(StockStr, 10, 0)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "stock must be int", "INVALID_PRODUCT")
+			return
+		}
+
+		if StockVal < 3 {
+			writeError(w, r, http.StatusBadRequest, "stock must be >= 3", "INVALID_PRODUCT")
+			return
+		}
+
+		if StockVal > 8 {
+			writeError(w, r, http.StatusBadRequest, "stock must be <= 8", "INVALID_PRODUCT")
+			return
+		}
+
+		params.Stock = int(StockVal)
+	}
+
+	ActiveStr := queryParams.Get("active")
+
+	if !queryParams.Has("active") {
+		ActiveStr = "true"
+	}
+
+	if ActiveStr != "" {
+		switch strings.ToLower(ActiveStr) {
+		case "on":
+			params.Active = true
+		case "off":
+			params.Active = false
+		default:
+			ActiveVal, err := strconv.ParseBool(ActiveStr)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "active must be bool", "INVALID_PRODUCT")
+				return
+			}
+			params.Active = ActiveVal
+		}
+	}
+
+	PriceStr := queryParams.Get("price")
+
+	if PriceStr != "" {
+		PriceVal, err := strconv.ParseFloat(PriceStr, 64)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "price must be float", "INVALID_PRODUCT")
+			return
+		}
+
+		if PriceVal <= 0 {
+			writeError(w, r, http.StatusBadRequest, "price must be > 0", "INVALID_PRODUCT")
+			return
+		}
+
+		if PriceVal > 9999.99 {
+			writeError(w, r, http.StatusBadRequest, "price must be <= 9999.99", "INVALID_PRODUCT")
+			return
+		}
+
+		params.Price = float64(PriceVal)
+	}
+
+	res, err := h.Create(r.Context(), params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusCreated, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusCreated, responseEnvelope{Response: res})
+}
+
+var regexProductApiUpdateSku = regexp.MustCompile("^[A-Z]{3}-\\d+$")
+
+func (h *ProductApi) handlerUpdate(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "PUT":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params ProductUpdateParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	params.Sku = queryParams.Get("sku")
+
+	if params.Sku == "" {
+		writeError(w, r, http.StatusBadRequest, "sku must be not empty", "VALIDATION_ERROR")
+		return
+	}
+
+	if params.Sku != "" && !regexProductApiUpdateSku.MatchString(params.Sku) {
+		writeError(w, r, http.StatusBadRequest, "sku must match pattern ^[A-Z]{3}-\\d+$", "VALIDATION_ERROR")
+		return
+	}
+
+	StockStr := queryParams.Get("stock")
+
+	if StockStr != "" {
+		StockVal, err := strconv.ParseInt(StockStr, 10, 0)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "stock must be int", "VALIDATION_ERROR")
+			return
+		}
+
+		if StockVal < 0 {
+			writeError(w, r, http.StatusBadRequest, "stock must be >= 0", "VALIDATION_ERROR")
+			return
+		}
+
+		params.Stock = int(StockVal)
+	}
+
+	OnSaleStr := queryParams.Get("on_sale")
+
+	if OnSaleStr != "" {
+		switch strings.ToLower(OnSaleStr) {
+		case "on":
+			params.OnSale = true
+		case "off":
+			params.OnSale = false
+		default:
+			OnSaleVal, err := strconv.ParseBool(OnSaleStr)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, "on_sale must be bool", "VALIDATION_ERROR")
+				return
+			}
+			params.OnSale = OnSaleVal
+		}
+	}
+
+	params.Discount = queryParams.Get("discount_code")
+
+	params.Revision = queryParams.Get("revision")
+
+	if params.Revision != "" && !isUUIDProductApi(params.Revision) {
+		writeError(w, r, http.StatusBadRequest, "revision must be a valid UUID", "VALIDATION_ERROR")
+		return
+	}
+
+	if params.OnSale && queryParams.Get("discount_code") == "" {
+		writeError(w, r, http.StatusBadRequest, "discount_code must be not empty when on_sale is true", "VALIDATION_ERROR")
+		return
+	}
+
+	res, err := h.Update(r.Context(), params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+func (h *ProductApi) handlerDelete(w http.ResponseWriter, r *http.Request) {
+
+	authKey := os.Getenv("MY_API_KEY")
+	if authKey == "" {
+		writeError(w, r, http.StatusInternalServerError, "Server configuration error: missing auth key", "")
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Api-Key")), []byte(authKey)) != 1 {
+		writeError(w, r, http.StatusForbidden, "unauthorized", "")
+		return
+	}
+
+	switch r.Method {
+	case "DELETE":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params ProductDeleteParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	params.Sku = queryParams.Get("sku")
+
+	if params.Sku == "" {
+		writeError(w, r, http.StatusBadRequest, "sku must be not empty", "VALIDATION_ERROR")
+		return
+	}
+
+	res, err := h.Delete(r.Context(), params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+func (h *ProductApi) handlerArchive(w http.ResponseWriter, r *http.Request) {
+
+	authKey := os.Getenv("MY_API_KEY")
+	if authKey == "" {
+		writeError(w, r, http.StatusInternalServerError, "Server configuration error: missing auth key", "")
+		return
+	}
+
+	authToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(authToken), []byte(authKey)) != 1 {
+		writeError(w, r, http.StatusForbidden, "unauthorized", "")
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params ProductArchiveParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	params.Sku = queryParams.Get("sku")
+
+	if params.Sku == "" {
+		writeError(w, r, http.StatusBadRequest, "sku must be not empty", "VALIDATION_ERROR")
+		return
+	}
+
+	res, err := h.Archive(r.Context(), params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+func (h *ProductApi) handlerStock(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params ProductStockParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	params.Sku = queryParams.Get("sku")
+
+	if params.Sku == "" {
+		writeError(w, r, http.StatusBadRequest, "sku must be not empty", "VALIDATION_ERROR")
+		return
+	}
+
+	DelayStr := queryParams.Get("delay")
+
+	if DelayStr != "" {
+		DelayVal, err := strconv.ParseInt(DelayStr, 10, 32)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "delay must be int32", "VALIDATION_ERROR")
+			return
+		}
+
+		if DelayVal < 0 {
+			writeError(w, r, http.StatusBadRequest, "delay must be >= 0", "VALIDATION_ERROR")
+			return
+		}
+
+		if DelayVal >= 1000 {
+			writeError(w, r, http.StatusBadRequest, "delay must be < 1000", "VALIDATION_ERROR")
+			return
+		}
+
+		params.Delay = int32(DelayVal)
+	}
+
+	WarehouseStr := queryParams.Get("warehouse")
+
+	if WarehouseStr != "" {
+		WarehouseVal, err := strconv.ParseUint(WarehouseStr, 10, 64)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "warehouse must be uint64", "VALIDATION_ERROR")
+			return
+		}
+
+		params.Warehouse = uint64(WarehouseVal)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
+	defer cancel()
+	res, err := h.Stock(ctx, params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+func (h *ProductApi) handlerReview(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params ProductReviewParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1024)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	var validationErrors []string
+
+	if msg := func() string {
+
+		params.Sku = queryParams.Get("sku")
+
+		if params.Sku == "" {
+			return "sku must be not empty"
+		}
+
+		return ""
+	}(); msg != "" {
+		validationErrors = append(validationErrors, msg)
+	}
+
+	if msg := func() string {
+
+		RatingStr := queryParams.Get("rating")
+
+		if RatingStr != "" {
+			RatingVal, err := strconv.ParseInt(RatingStr, 10, 0)
+			if err != nil {
+				return "rating must be int"
+			}
+
+			if RatingVal < 1 {
+				return "rating must be >= 1"
+			}
+
+			if RatingVal > 5 {
+				return "rating must be <= 5"
+			}
+
+			params.Rating = int(RatingVal)
+		}
+
+		return ""
+	}(); msg != "" {
+		validationErrors = append(validationErrors, msg)
+	}
+
+	if msg := func() string {
+
+		params.Text = queryParams.Get("text")
+
+		if len(params.Text) > 140 {
+			return "text len must be <= 140"
+		}
+
+		return ""
+	}(); msg != "" {
+		validationErrors = append(validationErrors, msg)
+	}
+
+	if len(validationErrors) > 0 {
+		if acceptsXML(r) {
+			writeXML(w, http.StatusBadRequest, xmlEnvelope{Errors: validationErrors})
+			return
+		}
+		body, _ := json.Marshal(map[string][]string{"errors": validationErrors})
+		http.Error(w, string(body), http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.Review(r.Context(), params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+var enumProductApiListSort = []string{"name", "price"}
+
+var enumProductApiListStatus = map[int]bool{0: true, 1: true, 2: true}
+
+func (h *ProductApi) handlerList(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+	default:
+		writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+		return
+	}
+
+	var params ProductListParams
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+
+	var queryParams url.Values
+	if r.Method == "GET" {
+		queryParams = r.URL.Query()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		err := decoder.Decode(&body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+			return
+		}
+		queryParams = url.Values{}
+		for key, value := range body {
+			switch v := value.(type) {
+			case string:
+				queryParams.Set(key, v)
+			case json.Number:
+				queryParams.Set(key, v.String())
+			case bool:
+				queryParams.Set(key, strconv.FormatBool(v))
+			}
+		}
+	} else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+		return
+	} else {
+		var err error
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			err = r.ParseMultipartForm(1048576)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		queryParams = r.Form
+	}
+
+	LimitStr := queryParams.Get("limit")
+
+	if !queryParams.Has("limit") {
+		LimitStr = "20"
+	}
+
+	if LimitStr != "" {
+		LimitVal, err := strconv.ParseInt(LimitStr, 10, 0)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "limit must be int", "VALIDATION_ERROR")
+			return
+		}
+
+		if LimitVal < 1 {
+			writeError(w, r, http.StatusBadRequest, "limit must be >= 1", "VALIDATION_ERROR")
+			return
+		}
+
+		if LimitVal > 100 {
+			writeError(w, r, http.StatusBadRequest, "limit must be <= 100", "VALIDATION_ERROR")
+			return
+		}
+
+		if LimitVal%10 != 0 {
+			writeError(w, r, http.StatusBadRequest, "limit must be a multiple of 10", "VALIDATION_ERROR")
+			return
+		}
+
+		params.Limit = int(LimitVal)
+	}
+
+	OffsetStr := queryParams.Get("offset")
+
+	if OffsetStr != "" {
+		OffsetVal, err := strconv.ParseInt(OffsetStr, 10, 0)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "offset must be int", "VALIDATION_ERROR")
+			return
+		}
+
+		if OffsetVal < 0 {
+			writeError(w, r, http.StatusBadRequest, "offset must be >= 0", "VALIDATION_ERROR")
+			return
+		}
+
+		params.Offset = int(OffsetVal)
+	}
+
+	params.Owner = queryParams.Get("owner")
+
+	if params.Owner == "" {
+		writeError(w, r, http.StatusBadRequest, "owner must be not empty", "VALIDATION_ERROR")
+		return
+	}
+
+	if params.Owner != "" {
+		if _, err := mail.ParseAddress(params.Owner); err != nil {
+			writeError(w, r, http.StatusBadRequest, "owner must be a valid email", "VALIDATION_ERROR")
+			return
+		}
+	}
+
+	params.Sort = queryParams.Get("sort")
+
+	SortValid := false
+	for _, v := range enumProductApiListSort {
+		if strings.EqualFold(params.Sort, v) {
+			params.Sort = v
+			SortValid = true
+			break
+		}
+	}
+	if !SortValid && params.Sort != "" {
+		writeError(w, r, http.StatusBadRequest, "sort must be one of [name, price]", "VALIDATION_ERROR")
+		return
+	}
+
+	if !queryParams.Has("sort") {
+		params.Sort = "name"
+	}
+
+	StatusStr := queryParams.Get("status")
+
+	if StatusStr != "" {
+		StatusVal, err := strconv.ParseInt(StatusStr, 10, 0)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "status must be int", "VALIDATION_ERROR")
+			return
+		}
+
+		if !enumProductApiListStatus[int(StatusVal)] {
+			writeError(w, r, http.StatusBadRequest, "status must be one of [0, 1, 2]", "VALIDATION_ERROR")
+			return
+		}
+
+		params.Status = int(StatusVal)
+	}
+
+	SinceStr := queryParams.Get("since")
+
+	if SinceStr != "" {
+		SinceVal, err := time.Parse("2006-01-02", SinceStr)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "since must be a date in the layout 2006-01-02", "VALIDATION_ERROR")
+			return
+		}
+		params.Since = SinceVal
+	}
+
+	params.Until = queryParams.Get("until")
+
+	if params.Until != "" {
+		if _, err := time.Parse("2006-01-02", params.Until); err != nil {
+			writeError(w, r, http.StatusBadRequest, "until must be a date in the layout 2006-01-02", "VALIDATION_ERROR")
+			return
+		}
+	}
+
+	res, err := h.List(r.Context(), params)
+
+	if err != nil {
+		status := errorStatus(err)
+		writeError(w, r, status, err.Error(), "")
+		return
+	}
+	if acceptsXML(r) {
+		writeXML(w, http.StatusOK, xmlEnvelope{Response: res})
+		return
+	}
+	writeJSON(w, http.StatusOK, responseEnvelope{Response: res})
+}
+
+// introspectionProductApi describes the API methods of ProductApi.
+const introspectionProductApi = "{\"methods\":[{\"name\":\"Create\",\"url\":\"/product/create\",\"http_methods\":[\"POST\"],\"auth\":false,\"params\":[{\"name\":\"sku\",\"type\":\"string\",\"required\":true,\"regex\":\"^[A-Z]{3}-\\\\d+$\"},{\"name\":\"code\",\"type\":\"string\",\"regex\":\"^[a-z]{2,4}$\"},{\"name\":\"owner\",\"type\":\"string\",\"required\":true,\"email\":true},{\"name\":\"title\",\"type\":\"string\",\"min\":3,\"max\":8},{\"name\":\"stock\",\"type\":\"int\",\"min\":3,\"max\":8},{\"name\":\"active\",\"type\":\"bool\",\"default\":\"true\"},{\"name\":\"price\",\"type\":\"float64\",\"min\":0,\"max\":9999.99,\"min_exclusive\":true}]},{\"name\":\"Update\",\"url\":\"/product/update\",\"http_methods\":[\"PUT\"],\"auth\":false,\"params\":[{\"name\":\"sku\",\"type\":\"string\",\"required\":true,\"regex\":\"^[A-Z]{3}-\\\\d+$\"},{\"name\":\"stock\",\"type\":\"int\",\"min\":0},{\"name\":\"on_sale\",\"type\":\"bool\"},{\"name\":\"discount_code\",\"type\":\"string\"},{\"name\":\"revision\",\"type\":\"string\",\"uuid\":true}]},{\"name\":\"Delete\",\"url\":\"/product/delete\",\"http_methods\":[\"DELETE\"],\"auth\":true,\"params\":[{\"name\":\"sku\",\"type\":\"string\",\"required\":true}]},{\"name\":\"Archive\",\"url\":\"/product/archive\",\"http_methods\":[\"POST\"],\"auth\":true,\"params\":[{\"name\":\"sku\",\"type\":\"string\",\"required\":true}]},{\"name\":\"Stock\",\"url\":\"/product/stock\",\"http_methods\":[\"GET\"],\"auth\":false,\"params\":[{\"name\":\"sku\",\"type\":\"string\",\"required\":true},{\"name\":\"delay\",\"type\":\"int32\",\"min\":0,\"max\":1000,\"max_exclusive\":true},{\"name\":\"warehouse\",\"type\":\"uint64\"}]},{\"name\":\"Review\",\"url\":\"/product/review\",\"http_methods\":[\"POST\"],\"auth\":false,\"params\":[{\"name\":\"sku\",\"type\":\"string\",\"required\":true},{\"name\":\"rating\",\"type\":\"int\",\"min\":1,\"max\":5},{\"name\":\"text\",\"type\":\"string\",\"max\":140}]},{\"name\":\"List\",\"url\":\"/product/list\",\"http_methods\":[\"GET\"],\"auth\":false,\"params\":[{\"name\":\"limit\",\"type\":\"int\",\"min\":1,\"max\":100,\"multiple_of\":10,\"default\":\"20\"},{\"name\":\"offset\",\"type\":\"int\",\"min\":0},{\"name\":\"owner\",\"type\":\"string\",\"required\":true,\"email\":true},{\"name\":\"sort\",\"type\":\"string\",\"enum\":[\"name\",\"price\"],\"default\":\"name\"},{\"name\":\"status\",\"type\":\"int\",\"enum\":[\"0\",\"1\",\"2\"]},{\"name\":\"since\",\"type\":\"time.Time\",\"datetime\":\"2006-01-02\"},{\"name\":\"until\",\"type\":\"string\",\"datetime\":\"2006-01-02\"}]}]}\n"
+
+// isUUIDProductApi reports whether s is a UUID in the canonical
+// 8-4-4-4-12 hexadecimal form.
+func isUUIDProductApi(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RegisterProductApiRoutes registers srv on mux for the URL of every API method
+// of ProductApi, so it can be served alongside other routes.
+// Routes shared by all receivers are only registered if mux does not serve them yet.
+func RegisterProductApiRoutes(mux *http.ServeMux, srv *ProductApi) {
+	mux.Handle("/product/create", srv)
+	mux.Handle("/product/update", srv)
+	mux.Handle("/product/delete", srv)
+	mux.Handle("/product/archive", srv)
+	mux.Handle("/product/stock", srv)
+	mux.Handle("/product/review", srv)
+	mux.Handle("/product/list", srv)
+	if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "/_introspect"}}); pattern == "" {
+		mux.Handle("/_introspect", srv)
+	}
+	if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "/healthz"}}); pattern == "" {
+		mux.Handle("/healthz", srv)
+	}
+}
+
+func (h *ProductApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if acceptsGzip(r) {
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.Close()
+		w = gw
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			log.Printf("panic serving %s: %v", r.URL.Path, err)
+			writeError(w, r, http.StatusInternalServerError, "internal server error", "")
+		}
+	}()
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		switch {
+		case r.URL.Path == "/product/create":
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/product/update":
+			w.Header().Set("Access-Control-Allow-Methods", "PUT")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/product/delete":
+			w.Header().Set("Access-Control-Allow-Methods", "DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Api-Key")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/product/archive":
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/product/stock":
+			w.Header().Set("Access-Control-Allow-Methods", "GET")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/product/review":
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/product/list":
+			w.Header().Set("Access-Control-Allow-Methods", "GET")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/_introspect":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(introspectionProductApi))
+	case "/healthz":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"status\":\"ok\"}"))
+
+	case "/product/create":
+		h.handlerCreate(w, r)
+
+	case "/product/update":
+		h.handlerUpdate(w, r)
+
+	case "/product/delete":
+		h.handlerDelete(w, r)
+
+	case "/product/archive":
+		h.handlerArchive(w, r)
+
+	case "/product/stock":
+		h.handlerStock(w, r)
+
+	case "/product/review":
+		h.handlerReview(w, r)
+
+	case "/product/list":
+		h.handlerList(w, r)
+
+	default:
+		writeError(w, r, http.StatusNotFound, "unknown method", "")
 	}
 }
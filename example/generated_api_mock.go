@@ -0,0 +1,294 @@
+// Code generated by gonerator from api.go; DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// MyApiInterface is the set of API methods implemented by MyApi.
+type MyApiInterface interface {
+	Profile(ctx context.Context, in ProfileParams) (*User, error)
+
+	Create(ctx context.Context, in CreateParams) (*NewUser, error)
+
+	User(ctx context.Context, in UserParams) (*User, error)
+
+	Whoami(ctx context.Context, in WhoamiParams, r *http.Request) (*Client, error)
+}
+
+var (
+	_ MyApiInterface = (*MyApi)(nil)
+	_ MyApiInterface = (*MyApiMock)(nil)
+)
+
+// MyApiMock is a mock implementation of MyApiInterface.
+// Every call is recorded in the <Method>Calls field. A call returns the result
+// of <Method>Func if it is set, and <Method>Result and <Method>Err otherwise.
+type MyApiMock struct {
+	mu sync.Mutex
+
+	ProfileCalls  []ProfileParams
+	ProfileFunc   func(ctx context.Context, in ProfileParams) (*User, error)
+	ProfileResult *User
+	ProfileErr    error
+
+	CreateCalls  []CreateParams
+	CreateFunc   func(ctx context.Context, in CreateParams) (*NewUser, error)
+	CreateResult *NewUser
+	CreateErr    error
+
+	UserCalls  []UserParams
+	UserFunc   func(ctx context.Context, in UserParams) (*User, error)
+	UserResult *User
+	UserErr    error
+
+	WhoamiCalls  []WhoamiParams
+	WhoamiFunc   func(ctx context.Context, in WhoamiParams, r *http.Request) (*Client, error)
+	WhoamiResult *Client
+	WhoamiErr    error
+}
+
+// Profile records the call and returns the programmed result.
+func (m *MyApiMock) Profile(ctx context.Context, in ProfileParams) (*User, error) {
+	m.mu.Lock()
+	m.ProfileCalls = append(m.ProfileCalls, in)
+	fn, res, err := m.ProfileFunc, m.ProfileResult, m.ProfileErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// Create records the call and returns the programmed result.
+func (m *MyApiMock) Create(ctx context.Context, in CreateParams) (*NewUser, error) {
+	m.mu.Lock()
+	m.CreateCalls = append(m.CreateCalls, in)
+	fn, res, err := m.CreateFunc, m.CreateResult, m.CreateErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// User records the call and returns the programmed result.
+func (m *MyApiMock) User(ctx context.Context, in UserParams) (*User, error) {
+	m.mu.Lock()
+	m.UserCalls = append(m.UserCalls, in)
+	fn, res, err := m.UserFunc, m.UserResult, m.UserErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// Whoami records the call and returns the programmed result.
+func (m *MyApiMock) Whoami(ctx context.Context, in WhoamiParams, r *http.Request) (*Client, error) {
+	m.mu.Lock()
+	m.WhoamiCalls = append(m.WhoamiCalls, in)
+	fn, res, err := m.WhoamiFunc, m.WhoamiResult, m.WhoamiErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in, r)
+	}
+	return res, err
+}
+
+// OtherApiInterface is the set of API methods implemented by OtherApi.
+type OtherApiInterface interface {
+	Create(ctx context.Context, in OtherCreateParams) (*OtherUser, error)
+}
+
+var (
+	_ OtherApiInterface = (*OtherApi)(nil)
+	_ OtherApiInterface = (*OtherApiMock)(nil)
+)
+
+// OtherApiMock is a mock implementation of OtherApiInterface.
+// Every call is recorded in the <Method>Calls field. A call returns the result
+// of <Method>Func if it is set, and <Method>Result and <Method>Err otherwise.
+type OtherApiMock struct {
+	mu sync.Mutex
+
+	CreateCalls  []OtherCreateParams
+	CreateFunc   func(ctx context.Context, in OtherCreateParams) (*OtherUser, error)
+	CreateResult *OtherUser
+	CreateErr    error
+}
+
+// Create records the call and returns the programmed result.
+func (m *OtherApiMock) Create(ctx context.Context, in OtherCreateParams) (*OtherUser, error) {
+	m.mu.Lock()
+	m.CreateCalls = append(m.CreateCalls, in)
+	fn, res, err := m.CreateFunc, m.CreateResult, m.CreateErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// ProductApiInterface is the set of API methods implemented by ProductApi.
+type ProductApiInterface interface {
+	Create(ctx context.Context, in ProductCreateParams) (*Product, error)
+
+	Update(ctx context.Context, in ProductUpdateParams) (*Product, error)
+
+	Delete(ctx context.Context, in ProductDeleteParams) (*Product, error)
+
+	Archive(ctx context.Context, in ProductArchiveParams) (*Product, error)
+
+	Stock(ctx context.Context, in ProductStockParams) (*Product, error)
+
+	Review(ctx context.Context, in ProductReviewParams) (*ProductReview, error)
+
+	List(ctx context.Context, in ProductListParams) (*ProductList, error)
+}
+
+var (
+	_ ProductApiInterface = (*ProductApi)(nil)
+	_ ProductApiInterface = (*ProductApiMock)(nil)
+)
+
+// ProductApiMock is a mock implementation of ProductApiInterface.
+// Every call is recorded in the <Method>Calls field. A call returns the result
+// of <Method>Func if it is set, and <Method>Result and <Method>Err otherwise.
+type ProductApiMock struct {
+	mu sync.Mutex
+
+	CreateCalls  []ProductCreateParams
+	CreateFunc   func(ctx context.Context, in ProductCreateParams) (*Product, error)
+	CreateResult *Product
+	CreateErr    error
+
+	UpdateCalls  []ProductUpdateParams
+	UpdateFunc   func(ctx context.Context, in ProductUpdateParams) (*Product, error)
+	UpdateResult *Product
+	UpdateErr    error
+
+	DeleteCalls  []ProductDeleteParams
+	DeleteFunc   func(ctx context.Context, in ProductDeleteParams) (*Product, error)
+	DeleteResult *Product
+	DeleteErr    error
+
+	ArchiveCalls  []ProductArchiveParams
+	ArchiveFunc   func(ctx context.Context, in ProductArchiveParams) (*Product, error)
+	ArchiveResult *Product
+	ArchiveErr    error
+
+	StockCalls  []ProductStockParams
+	StockFunc   func(ctx context.Context, in ProductStockParams) (*Product, error)
+	StockResult *Product
+	StockErr    error
+
+	ReviewCalls  []ProductReviewParams
+	ReviewFunc   func(ctx context.Context, in ProductReviewParams) (*ProductReview, error)
+	ReviewResult *ProductReview
+	ReviewErr    error
+
+	ListCalls  []ProductListParams
+	ListFunc   func(ctx context.Context, in ProductListParams) (*ProductList, error)
+	ListResult *ProductList
+	ListErr    error
+}
+
+// Create records the call and returns the programmed result.
+func (m *ProductApiMock) Create(ctx context.Context, in ProductCreateParams) (*Product, error) {
+	m.mu.Lock()
+	m.CreateCalls = append(m.CreateCalls, in)
+	fn, res, err := m.CreateFunc, m.CreateResult, m.CreateErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// Update records the call and returns the programmed result.
+func (m *ProductApiMock) Update(ctx context.Context, in ProductUpdateParams) (*Product, error) {
+	m.mu.Lock()
+	m.UpdateCalls = append(m.UpdateCalls, in)
+	fn, res, err := m.UpdateFunc, m.UpdateResult, m.UpdateErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// Delete records the call and returns the programmed result.
+func (m *ProductApiMock) Delete(ctx context.Context, in ProductDeleteParams) (*Product, error) {
+	m.mu.Lock()
+	m.DeleteCalls = append(m.DeleteCalls, in)
+	fn, res, err := m.DeleteFunc, m.DeleteResult, m.DeleteErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// Archive records the call and returns the programmed result.
+func (m *ProductApiMock) Archive(ctx context.Context, in ProductArchiveParams) (*Product, error) {
+	m.mu.Lock()
+	m.ArchiveCalls = append(m.ArchiveCalls, in)
+	fn, res, err := m.ArchiveFunc, m.ArchiveResult, m.ArchiveErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// Stock records the call and returns the programmed result.
+func (m *ProductApiMock) Stock(ctx context.Context, in ProductStockParams) (*Product, error) {
+	m.mu.Lock()
+	m.StockCalls = append(m.StockCalls, in)
+	fn, res, err := m.StockFunc, m.StockResult, m.StockErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// Review records the call and returns the programmed result.
+func (m *ProductApiMock) Review(ctx context.Context, in ProductReviewParams) (*ProductReview, error) {
+	m.mu.Lock()
+	m.ReviewCalls = append(m.ReviewCalls, in)
+	fn, res, err := m.ReviewFunc, m.ReviewResult, m.ReviewErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
+
+// List records the call and returns the programmed result.
+func (m *ProductApiMock) List(ctx context.Context, in ProductListParams) (*ProductList, error) {
+	m.mu.Lock()
+	m.ListCalls = append(m.ListCalls, in)
+	fn, res, err := m.ListFunc, m.ListResult, m.ListErr
+	m.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, in)
+	}
+	return res, err
+}
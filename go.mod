@@ -1,3 +1,5 @@
 module github.com/notrightending/gonerator
 
 go 1.22.4
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change of a diff.
+const diffContext = 3
+
+// CheckPackage is like GeneratePackage but compares the generated code with
+// the existing outputFile instead of writing anything. It returns a unified
+// diff from the existing file to the generated code, or an empty string if
+// the file is up to date. A missing outputFile is compared as an empty file.
+func CheckPackage(inputFiles []string, outputFile string, opts Options) (string, error) {
+	if len(inputFiles) == 0 {
+		return "", fmt.Errorf("no input files")
+	}
+
+	_, code, err := generateCode(inputFiles, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return checkFile(outputFile, code)
+}
+
+// CheckFiles is like GenerateFiles but compares the generated code with the
+// existing output files instead of writing anything. It returns the diffs of
+// all output files that are not up to date, or an empty string.
+func CheckFiles(inputFiles []string, outPattern string, opts Options) (string, error) {
+	var diffs strings.Builder
+	err := generateFiles(inputFiles, outPattern, opts, false, func(outputFile string, code []byte) error {
+		diff, err := checkFile(outputFile, code)
+		diffs.WriteString(diff)
+		return err
+	})
+	return diffs.String(), err
+}
+
+// CheckDir is like GenerateDirWithOptions but compares the generated code
+// with the existing output files like CheckFiles.
+func CheckDir(dir, outPattern string, opts Options) (string, error) {
+	inputFiles, opts, err := dirInputs(dir, opts)
+	if err != nil {
+		return "", err
+	}
+	return CheckFiles(inputFiles, outPattern, opts)
+}
+
+// checkFile returns the diff from the contents of outputFile to code.
+func checkFile(outputFile string, code []byte) (string, error) {
+	existing, err := os.ReadFile(outputFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	return lineDiff(outputFile, existing, code), nil
+}
+
+// diffOp is a line of a diff: an unchanged (' '), removed ('-') or added ('+')
+// line, with the line indexes in the old and new text it is found at.
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+// lineDiff returns a unified diff of the lines of old and new, both
+// named name, or an empty string if they are equal.
+func lineDiff(name string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+	a := splitLines(old)
+	b := splitLines(new)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s (generated)\n", name, name)
+	for start := 0; start < len(ops); {
+		// Find the next change and the end of the hunk around it
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		end := first
+		for k := first; k < len(ops) && k <= end+2*diffContext; k++ {
+			if ops[k].kind != ' ' {
+				end = k
+			}
+		}
+		from := max(first-diffContext, start)
+		to := min(end+diffContext+1, len(ops))
+
+		var oldLines, newLines int
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				oldLines++
+			}
+			if op.kind != '-' {
+				newLines++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", ops[from].a+1, oldLines, ops[from].b+1, newLines)
+		for _, op := range ops[from:to] {
+			fmt.Fprintf(&buf, "%c%s\n", op.kind, op.line)
+		}
+		start = to
+	}
+	return buf.String()
+}
+
+// splitLines splits text into lines without their line endings.
+func splitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+}
@@ -0,0 +1,398 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var clientFuncMap = template.FuncMap{
+	"clientMethod": clientMethod,
+	"pathParam":    pathParam,
+	"nonZero":      nonZero,
+	"formatParam":  formatParam,
+	"qualifyInput": qualifyInput,
+	"qualifyType":  qualifyType,
+}
+
+// clientMethod returns the HTTP method the client uses for an API method
+// configured with methods, which is the first one listed.
+func clientMethod(methods string) string {
+	method, _, _ := strings.Cut(methods, ",")
+	return strings.TrimSpace(method)
+}
+
+// pathParam reports whether field is a parameter of the URL of method.
+func pathParam(method Method, field StructField) bool {
+	for _, param := range method.ApiMethod.PathParams() {
+		if param == field.ParamName() {
+			return true
+		}
+	}
+	return false
+}
+
+// nonZero returns the condition that the field of the client input in
+// is not zero.
+func nonZero(field StructField) string {
+	switch {
+	case field.IsString():
+		return "in." + field.Name + ` != ""`
+	case field.IsTime():
+		return "!in." + field.Name + ".IsZero()"
+	}
+	return "in." + field.Name + " != 0"
+}
+
+// formatParam returns the expression formatting the field of the client
+// input in as a request parameter.
+func formatParam(field StructField) string {
+	value := "in." + field.Name
+	switch {
+	case field.IsInteger() && field.IsUnsigned():
+		return "strconv.FormatUint(uint64(" + value + "), 10)"
+	case field.IsInteger():
+		return "strconv.FormatInt(int64(" + value + "), 10)"
+	case field.IsFloat():
+		return fmt.Sprintf("strconv.FormatFloat(float64(%s), 'g', -1, %d)", value, field.FloatBits())
+	case field.IsBool():
+		return "strconv.FormatBool(" + value + ")"
+	case field.IsTime():
+		return value + ".Format(" + strconv.Quote(field.TimeLayout()) + ")"
+	}
+	return value
+}
+
+// qualifyInput returns the input parameter type of method as used from
+// the client package, which imports the package of method as server.
+func qualifyInput(server string, method Method) string {
+	if method.InputImport.Path != "" {
+		return method.InputParam()
+	}
+	return qualifyType(server, method.InputParam())
+}
+
+// qualifyType returns typ, a type of the package imported as server, with
+// the type names declared in that package qualified by server.
+func qualifyType(server, typ string) string {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return typ
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && !predeclaredType(ident.Name) {
+			ident.Name = server + "." + ident.Name
+		}
+		return true
+	})
+	return types.ExprString(expr)
+}
+
+// predeclaredType reports whether name is a predeclared type like int.
+func predeclaredType(name string) bool {
+	_, ok := types.Universe.Lookup(name).(*types.TypeName)
+	return ok
+}
+
+// unexportedType returns the first type name of typ declared in its
+// package that is not exported, or "" if there is none.
+func unexportedType(typ string) string {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return ""
+	}
+	var name string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && name == "" && !predeclaredType(ident.Name) && !token.IsExported(ident.Name) {
+			name = ident.Name
+		}
+		return true
+	})
+	return name
+}
+
+// clientImportNames are the package names the client template imports.
+var clientImportNames = []string{"context", "errors", "http", "json", "strconv", "strings", "url"}
+
+// renderClient returns the client of methods, declared in the package
+// packageName, to be written to clientFile. The client is generated in its
+// own package, named after the directory of clientFile, which imports the
+// package of methods for their input and result types and ApiError.
+func renderClient(packageName string, methods []Method, clientFile string, opts Options) ([]byte, error) {
+	clientDir, err := filepath.Abs(filepath.Dir(clientFile))
+	if err != nil {
+		return nil, err
+	}
+	var serverDir string
+	if len(methods) > 0 {
+		serverDir, err = filepath.Abs(filepath.Dir(methods[0].File))
+		if err != nil {
+			return nil, err
+		}
+		if serverDir == clientDir {
+			return nil, fmt.Errorf("the client must be written to another directory than %s, the package of the handlers", filepath.Dir(methods[0].File))
+		}
+	}
+	clientPackage := filepath.Base(clientDir)
+	if !token.IsIdentifier(clientPackage) {
+		return nil, fmt.Errorf("invalid client package name %q, the client must be written to a directory named like a Go package", clientPackage)
+	}
+
+	envelope, err := envelopeFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		PackageName string
+		Server      ImportSpec
+		Methods     map[string][]Method
+		Imports     []ImportSpec
+		Envelope    string
+		ErrorKey    string
+		ErrorsKey   string
+		ResponseKey string
+	}{
+		PackageName: clientPackage,
+		Methods:     make(map[string][]Method),
+		Imports:     inputImports(methods),
+		Envelope:    envelope.Name,
+		ErrorKey:    envelope.ErrorKey,
+		ErrorsKey:   envelope.ErrorsKey,
+		ResponseKey: envelope.ResponseKey,
+	}
+	for _, method := range methods {
+		data.Methods[method.ReceiverType] = append(data.Methods[method.ReceiverType], method)
+		if method.InputImport.Path == "" && !token.IsExported(method.InputType) {
+			return nil, fmt.Errorf("method %s: input type %s is not exported, so the client cannot use it", method.Name, method.InputType)
+		}
+		if name := unexportedType(method.OutputType); name != "" {
+			return nil, fmt.Errorf("method %s: result type %s is not exported, so the client cannot use it", method.Name, name)
+		}
+	}
+
+	if serverDir != "" {
+		data.Server.Path, err = packageImportPath(serverDir)
+		if err != nil {
+			return nil, err
+		}
+		// Rename the import of the input package if its name is taken
+		data.Server.Name = packageName
+		taken := append([]string{clientPackage}, clientImportNames...)
+		for _, spec := range data.Imports {
+			taken = append(taken, spec.Name)
+		}
+		for contains(taken, data.Server.Name) {
+			data.Server.Name += "api"
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(generatedHeader(methods))
+	err = clientTemplate.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+	return formatSource(buf.Bytes())
+}
+
+// contains reports whether list holds s.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// packageImportPath returns the import path of the package in dir, from
+// the module path in the go.mod of dir or of its nearest parent.
+func packageImportPath(dir string) (string, error) {
+	for moduleDir := dir; ; moduleDir = filepath.Dir(moduleDir) {
+		data, err := os.ReadFile(filepath.Join(moduleDir, "go.mod"))
+		if err == nil {
+			modulePath := modulePath(data)
+			if modulePath == "" {
+				return "", fmt.Errorf("no module path in %s", filepath.Join(moduleDir, "go.mod"))
+			}
+			rel, err := filepath.Rel(moduleDir, dir)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(modulePath, filepath.ToSlash(rel)), nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if filepath.Dir(moduleDir) == moduleDir {
+			return "", fmt.Errorf("cannot find the go.mod of %s, which the client imports", dir)
+		}
+	}
+}
+
+// modulePath returns the path of the module directive of a go.mod file,
+// or "" if there is none.
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if path, err := strconv.Unquote(fields[1]); err == nil {
+			return path
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+var clientTemplate = template.Must(template.New("client").Funcs(clientFuncMap).Parse(`
+package {{.PackageName}}
+
+import (
+    {{- if .Server.Path}}
+    {{.Server.Name}} "{{.Server.Path}}"
+    {{- end}}
+    {{- range .Imports}}
+    {{.Name}} "{{.Path}}"
+    {{- end}}
+)
+
+// isSet reports whether the parameter name is listed in set.
+func isSet(set []string, name string) bool {
+    for _, s := range set {
+        if s == name {
+            return true
+        }
+    }
+    return false
+}
+
+{{range $receiverType, $methods := .Methods}}
+// {{$receiverType}}Client is an HTTP client for the {{$receiverType}} API.
+// Its methods send the parameters of their input that are not zero, are
+// part of the URL or are listed in set by name, so a zero value can be
+// sent to a parameter with a default.
+type {{$receiverType}}Client struct {
+    // BaseURL is the URL the API is served at, without a trailing slash.
+    BaseURL string
+    // AuthKey is sent in the auth header of methods that require auth.
+    AuthKey string
+    // HTTPClient is used to send requests.
+    HTTPClient *http.Client
+}
+
+// New{{$receiverType}}Client creates a client for the {{$receiverType}} API served at baseURL.
+func New{{$receiverType}}Client(baseURL, authKey string) *{{$receiverType}}Client {
+    return &{{$receiverType}}Client{
+        BaseURL:    baseURL,
+        AuthKey:    authKey,
+        HTTPClient: http.DefaultClient,
+    }
+}
+
+{{range $methods}}
+{{- $method := .}}
+// {{.Name}} calls {{.ApiMethod.Url}}.
+func (c *{{$receiverType}}Client) {{.Name}}(ctx context.Context, in {{qualifyInput $.Server.Name .}}, set ...string) ({{qualifyType $.Server.Name .OutputResult}}, error) {
+    params := url.Values{}
+    {{- range .StructFields}}
+    {{- if or .IsInteger .IsFloat .IsBool .IsString .IsTime}}
+    {{- $optional := and (not .IsBool) (not (pathParam $method .))}}
+    {{- if $optional}}
+    if {{nonZero .}} || isSet(set, "{{.ParamName}}") {
+    {{- end}}
+    params.Set("{{.ParamName}}", {{formatParam .}})
+    {{- if $optional}}
+    }
+    {{- end}}
+    {{- end}}
+    {{- end}}
+
+    path := "{{.ApiMethod.Url}}"
+    {{- range .ApiMethod.PathParams}}
+    path = strings.Replace(path, "{{"{"}}{{.}}{{"}"}}", url.PathEscape(params.Get("{{.}}")), 1)
+    params.Del("{{.}}")
+    {{- end}}
+
+    var out {{qualifyType $.Server.Name .OutputType}}
+    err := c.do(ctx, "{{clientMethod .ApiMethod.Method}}", path, "{{if .ApiMethod.Auth}}{{.ApiMethod.AuthHeader}}{{end}}", "{{if eq .ApiMethod.AuthScheme "bearer"}}Bearer {{end}}", params, &out)
+    if err != nil {
+        return {{if .OutputPointer}}nil{{else}}out{{end}}, err
+    }
+    return {{if .OutputPointer}}&{{end}}out, nil
+}
+{{end}}
+
+// do sends params to path and decodes the response into out. AuthKey,
+// preceded by authPrefix, is sent in authHeader unless it is empty.
+// Error responses are returned as ApiError with the response status.
+func (c *{{$receiverType}}Client) do(ctx context.Context, method, path, authHeader, authPrefix string, params url.Values, out interface{}) error {
+    var req *http.Request
+    var err error
+    if method == http.MethodGet || method == http.MethodDelete {
+        req, err = http.NewRequestWithContext(ctx, method, c.BaseURL+path+"?"+params.Encode(), nil)
+    } else {
+        req, err = http.NewRequestWithContext(ctx, method, c.BaseURL+path, strings.NewReader(params.Encode()))
+        if err == nil {
+            req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+        }
+    }
+    if err != nil {
+        return err
+    }
+    if authHeader != "" {
+        req.Header.Set(authHeader, authPrefix+c.AuthKey)
+    }
+
+    resp, err := c.HTTPClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusNoContent {
+        return nil
+    }
+    success := resp.StatusCode >= 200 && resp.StatusCode <= 299
+    {{- if eq $.Envelope "bare"}}
+
+    if success {
+        return json.NewDecoder(resp.Body).Decode(out)
+    }
+    {{- end}}
+
+    var body struct {
+        Error    string          ` + "`json:\"{{$.ErrorKey}}\"`" + `
+        Errors   []string        ` + "`json:\"{{$.ErrorsKey}}\"`" + `
+        {{- if ne $.Envelope "bare"}}
+        Response json.RawMessage ` + "`json:\"{{$.ResponseKey}}\"`" + `
+        {{- end}}
+    }
+    err = json.NewDecoder(resp.Body).Decode(&body)
+    if err != nil {
+        return err
+    }
+    if !success {
+        if body.Error == "" {
+            body.Error = strings.Join(body.Errors, "; ")
+        }
+        return {{$.Server.Name}}.ApiError{HTTPStatus: resp.StatusCode, Err: errors.New(body.Error)}
+    }
+
+
+    {{if eq $.Envelope "bare"}}return nil{{else}}return json.Unmarshal(body.Response, out){{end}}
+}
+{{end}}
+`))
@@ -0,0 +1,263 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DefaultOutPattern is the output file name pattern used by GenerateDir
+// when none is given. {name} is replaced with the input file name without
+// its .go extension.
+const DefaultOutPattern = "{name}_gen.go"
+
+// DefaultExclude are the glob patterns of the files always skipped when a
+// directory is walked for input files: generated files of the default
+// output pattern, so they are not fed back in, and test files.
+var DefaultExclude = []string{"*_gen.go", "*_test.go"}
+
+// GenerateDir walks dir and generates handler code for every .go file that
+// contains at least one apigen:api method. Each output file is written next
+// to its input file using outPattern. Files without API methods are skipped.
+func GenerateDir(dir, outPattern string) error {
+	return GenerateDirWithOptions(dir, outPattern, Options{})
+}
+
+// GenerateDirWithOptions is like GenerateDir but allows customizing the output with opts.
+func GenerateDirWithOptions(dir, outPattern string, opts Options) error {
+	inputFiles, opts, err := dirInputs(dir, opts)
+	if err != nil {
+		return err
+	}
+	return GenerateFiles(inputFiles, outPattern, opts)
+}
+
+// dirInputs returns the input files of dir for opts, like sourceFiles, and
+// opts with patterns of opts.Exclude relative to dir.
+func dirInputs(dir string, opts Options) ([]string, Options, error) {
+	inputFiles, err := sourceFiles(dir, opts.Exclude)
+	opts.excludeRoot = dir
+	return inputFiles, opts, err
+}
+
+// sourceFiles returns the Go source files in dir and its subdirectories,
+// skipping the paths matching DefaultExclude or exclude.
+func sourceFiles(dir string, exclude []string) ([]string, error) {
+	patterns := append(append([]string{}, DefaultExclude...), exclude...)
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	var inputFiles []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && isExcluded(dir, path, patterns) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isSourceFile(path) {
+			return nil
+		}
+		inputFiles = append(inputFiles, path)
+		return nil
+	})
+	return inputFiles, err
+}
+
+// GenerateFiles generates handler code for every input file that contains
+// at least one apigen:api method, writing each output file next to its
+// input file using outPattern. Files without API methods are skipped.
+// Input structs are looked up in all files of the input file's package.
+//
+// The directories and files are processed concurrently, and every output
+// file is written by the worker that generated it. If any file fails, the
+// error of the first failing input file is returned.
+func GenerateFiles(inputFiles []string, outPattern string, opts Options) error {
+	return generateFiles(inputFiles, outPattern, opts, true, func(outputFile string, code []byte) error {
+		return writeFile(outputFile, code)
+	})
+}
+
+// generateFiles generates handler code for every input file like GenerateFiles
+// and passes it to emit along with the output file path. If concurrentEmit is
+// set, emit is called from the workers as soon as a file is generated.
+// Otherwise it is called in the order of inputFiles once all are generated.
+func generateFiles(inputFiles []string, outPattern string, opts Options, concurrentEmit bool, emit func(outputFile string, code []byte) error) error {
+	if outPattern == "" {
+		outPattern = DefaultOutPattern
+	}
+	err := checkOutputPaths(inputFiles, outPattern)
+	if err != nil {
+		return err
+	}
+	tmpl, err := loadHandlerTemplate(opts)
+	if err != nil {
+		return err
+	}
+
+	// Parse every directory once, keyed by directory
+	var dirNames []string
+	dirs := make(map[string]map[string]*parsedPackage)
+	for _, inputFile := range inputFiles {
+		dir := filepath.Dir(inputFile)
+		if _, ok := dirs[dir]; !ok {
+			dirs[dir] = nil
+			dirNames = append(dirNames, dir)
+		}
+	}
+	parsed := make([]map[string]*parsedPackage, len(dirNames))
+	err = parallel(len(dirNames), func(i int) error {
+		var err error
+		parsed[i], err = parseDir(dirNames[i], opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i, dir := range dirNames {
+		dirs[dir] = parsed[i]
+		warnDir(opts, parsed[i])
+	}
+
+	files := make([]*generatedFile, len(inputFiles))
+	err = parallel(len(inputFiles), func(i int) error {
+		inputFile := inputFiles[i]
+		var packageName string
+		var methods []Method
+		for _, pkg := range dirs[filepath.Dir(inputFile)] {
+			for _, method := range pkg.Methods {
+				if filepath.Clean(method.File) == filepath.Clean(inputFile) {
+					packageName = pkg.Name
+					methods = append(methods, method)
+				}
+			}
+		}
+		if len(methods) == 0 {
+			return nil
+		}
+
+		code, err := render(tmpl, packageName, methods, opts)
+		if err != nil {
+			return err
+		}
+
+		file := &generatedFile{path: OutputPath(inputFile, outPattern), code: code}
+		if concurrentEmit {
+			return emit(file.path, file.code)
+		}
+		files[i] = file
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+		err = emit(file.path, file.code)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parallel calls fn for every index below n on at most GOMAXPROCS goroutines
+// and waits for them. It returns the error of the lowest index that failed.
+// Once an index fails, the indexes not started yet are skipped.
+func parallel(n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	var failed sync.Once
+	done := make(chan struct{})
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+
+	var wg sync.WaitGroup
+start:
+	for i := 0; i < n; i++ {
+		select {
+		case <-done:
+			break start
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+			if errs[i] != nil {
+				failed.Do(func() { close(done) })
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutputPath returns the output file path for inputFile by expanding
+// {name} in outPattern. The result lives in the same directory as inputFile.
+func OutputPath(inputFile, outPattern string) string {
+	name := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+	return filepath.Join(filepath.Dir(inputFile), strings.ReplaceAll(outPattern, "{name}", name))
+}
+
+// checkOutputPaths returns an error if two of inputFiles would be written
+// to the same output file with outPattern, as with a pattern without
+// {name}, since only the last file written would survive.
+func checkOutputPaths(inputFiles []string, outPattern string) error {
+	inputs := make(map[string]string)
+	for _, inputFile := range inputFiles {
+		outputFile := filepath.Clean(OutputPath(inputFile, outPattern))
+		other, ok := inputs[outputFile]
+		if ok && filepath.Clean(other) != filepath.Clean(inputFile) {
+			return fmt.Errorf("input files %s and %s would both be written to %s, the output pattern must contain {name}", other, inputFile, outputFile)
+		}
+		inputs[outputFile] = inputFile
+	}
+	return nil
+}
+
+// isExcluded reports whether path, found by walking dir, matches any of
+// patterns either relative to dir or by its base name.
+func isExcluded(dir, path string, patterns []string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		rel = path
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcludedFile reports whether path matches one of opts.Exclude, relative
+// to the directory walked for input files if any, or by its base name.
+func isExcludedFile(path string, opts Options) bool {
+	return len(opts.Exclude) > 0 && isExcluded(opts.excludeRoot, path, opts.Exclude)
+}
+
+// isSourceFile reports whether path is a non-test Go source file.
+func isSourceFile(path string) bool {
+	return strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go")
+}
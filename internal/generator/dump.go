@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// DumpPackage parses inputFiles like GeneratePackage and returns the
+// parsed methods, with their ApiMethod and StructFields, as indented JSON.
+// Nothing is written. Of opts, only Lax and Warn are used.
+func DumpPackage(inputFiles []string, opts Options) ([]byte, error) {
+	if len(inputFiles) == 0 {
+		return nil, fmt.Errorf("no input files")
+	}
+
+	pkg, err := parseFiles(inputFiles, opts.Lax)
+	if err != nil {
+		return nil, err
+	}
+	warn(opts, pkg)
+	return dumpMethods(pkg.Methods)
+}
+
+// DumpFiles is like DumpPackage for the inputs of GenerateFiles: every
+// input file is parsed with the other files of its package, and only the
+// methods of inputFiles are dumped.
+func DumpFiles(inputFiles []string, opts Options) ([]byte, error) {
+	methods := []Method{}
+	parsed := make(map[string]map[string]*parsedPackage)
+	for _, inputFile := range inputFiles {
+		dir := filepath.Dir(inputFile)
+		if _, ok := parsed[dir]; !ok {
+			packages, err := parseDir(dir, opts)
+			if err != nil {
+				return nil, err
+			}
+			warnDir(opts, packages)
+			parsed[dir] = packages
+		}
+
+		names := make([]string, 0, len(parsed[dir]))
+		for name := range parsed[dir] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, method := range parsed[dir][name].Methods {
+				if filepath.Clean(method.File) == filepath.Clean(inputFile) {
+					methods = append(methods, method)
+				}
+			}
+		}
+	}
+	return dumpMethods(methods)
+}
+
+// DumpDir is like GenerateDirWithOptions but dumps the methods of the
+// source files of dir like DumpFiles. Of opts, only Lax, Warn and Exclude
+// are used.
+func DumpDir(dir string, opts Options) ([]byte, error) {
+	inputFiles, opts, err := dirInputs(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return DumpFiles(inputFiles, opts)
+}
+
+// dumpMethods returns methods as indented JSON.
+func dumpMethods(methods []Method) ([]byte, error) {
+	if methods == nil {
+		methods = []Method{}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(methods)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
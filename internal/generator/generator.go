@@ -2,28 +2,440 @@ package generator
 
 import (
 	"bytes"
-	"go/format"
-	"go/parser"
+	"fmt"
+	"go/build/constraint"
 	"go/token"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
 )
 
+// StdoutPath is the output path that makes Generate write to standard output.
+const StdoutPath = "-"
+
+// StdinPath is the input path that makes Generate read the source from standard input.
+const StdinPath = "-"
+
+// stdinName is the file name of source read from standard input in
+// error messages and the generated code header.
+const stdinName = "<stdin>"
+
+// Options configures code generation.
+type Options struct {
+	// PackageName overrides the package name of the generated file and
+	// must be a valid Go identifier. If empty, the package name of the
+	// input file is used.
+	PackageName string
+
+	// OpenAPIFile, if set, is the path an OpenAPI 3.0 spec describing
+	// the parsed methods is written to.
+	OpenAPIFile string
+
+	// PostmanFile, if set, is the path a Postman v2.1 collection with
+	// a request for every parsed method is written to.
+	PostmanFile string
+
+	// JSONSchemaDir, if set, is the directory a JSON Schema document
+	// of the input type of every method is written to. See JSONSchemaPath.
+	JSONSchemaDir string
+
+	// ClientFile, if set, is the path a typed HTTP client for the
+	// parsed methods is written to. The client is generated in its own
+	// package, named after the directory of ClientFile, which must not
+	// be the directory of the input package. It imports the input
+	// package, found through its go.mod, for the input and result types.
+	ClientFile string
+
+	// MocksFile, if set, is the path an interface and a mock
+	// implementation of each receiver type are written to.
+	MocksFile string
+
+	// TemplateFile, if set, is the path of a text/template the handlers
+	// are generated with instead of the built-in one. It is executed with
+	// the same data and helper functions, and must produce Go source.
+	TemplateFile string
+
+	// StrictMethods makes the handlers answer requests with a method
+	// that is not allowed with 405 Method Not Allowed and an Allow
+	// header instead of 406 Not Acceptable.
+	StrictMethods bool
+
+	// NoRecover disables recovering from panics of the API methods.
+	// By default the handlers log the panic and answer with 500
+	// Internal Server Error.
+	NoRecover bool
+
+	// Logging makes the handlers log the method, path, response status
+	// and duration of every request.
+	Logging bool
+
+	// Metrics makes the handlers count requests by path and status and
+	// observe their duration by path with Prometheus metrics. The
+	// generated code then depends on github.com/prometheus/client_golang.
+	Metrics bool
+
+	// Otel makes the handlers trace every request to an API method in an
+	// OpenTelemetry server span named after its HTTP method and route, with
+	// the response status and the error of the method. The spans are started
+	// with the global TracerProvider. The generated code then depends on
+	// go.opentelemetry.io/otel.
+	Otel bool
+
+	// CORSOrigin, if set, is sent in the Access-Control-Allow-Origin
+	// header of every response, and OPTIONS preflight requests are
+	// answered with the methods and headers each path accepts.
+	CORSOrigin string
+
+	// CORSMethods, if set, are sent in the Access-Control-Allow-Methods
+	// header of preflight responses instead of the methods each path
+	// accepts.
+	CORSMethods []string
+
+	// CORSHeaders are sent in the Access-Control-Allow-Headers header of
+	// preflight responses after the headers each path reads, e.g. headers
+	// read by a middleware.
+	CORSHeaders []string
+
+	// CollectErrors makes every handler validate all parameters and
+	// answer with the messages of all invalid ones in an "errors" array,
+	// as the collect_errors option of apigen:api does for one method.
+	CollectErrors bool
+
+	// HideInternalErrors makes the handlers answer errors of API methods
+	// with a 5xx status with {"error": "internal server error"} instead of
+	// the error message, which is logged instead.
+	HideInternalErrors bool
+
+	// Envelope is the shape of success responses: EnvelopeResponse, the
+	// default if empty, EnvelopeData or EnvelopeBare. Error responses are
+	// {"error": "<message>"} in every shape.
+	Envelope string
+
+	// ErrorKey, if set, renames the "error" key of error responses and of
+	// the response envelope, and ResponseKey the key results are wrapped
+	// in: "response" or "data", depending on Envelope. ResponseKey cannot
+	// be used with EnvelopeBare. The "errors" key of CollectErrors
+	// responses is ErrorKey with an s appended.
+	ErrorKey    string
+	ResponseKey string
+
+	// XML makes the handlers answer requests whose Accept header prefers
+	// application/xml with the result or error of the API method encoded
+	// as XML instead of JSON.
+	XML bool
+
+	// Gzip makes the handlers compress responses of at least GzipMinSize
+	// bytes with gzip if the Accept-Encoding header of the request allows it.
+	Gzip bool
+
+	// RequestID makes the handlers take the request ID from the
+	// X-Request-ID header, or generate a random UUID, echo it in the
+	// response and pass it to the API methods in the context, read with
+	// the generated RequestIDFromContext.
+	RequestID bool
+
+	// Slog implies RequestID and also passes a *slog.Logger with a
+	// request_id attribute in the context, read with the generated
+	// LoggerFromContext. With Logging, requests are logged with that logger.
+	Slog bool
+
+	// Router, if set, is the router an adapter registering the API
+	// methods is generated for: RouterChi or RouterGin.
+	Router string
+
+	// Introspect makes every receiver serve a JSON description of its API
+	// methods and their parameters at IntrospectURL.
+	Introspect bool
+
+	// Healthz makes every receiver answer liveness probes at HealthzURL
+	// with 200 OK and {"status":"ok"}, without authentication.
+	Healthz bool
+
+	// Lax makes a method whose input type is not a struct declared in the
+	// parsed files a warning passed to Warn instead of an error. Its
+	// handler is generated without parameters.
+	Lax bool
+
+	// Warn, if not nil, is called with each warning of the parser, such
+	// as the methods generated without parameters with Lax.
+	Warn func(warning string)
+
+	// Exclude lists glob patterns of the paths skipped when a directory is
+	// walked for input files, in addition to DefaultExclude. A pattern
+	// matches a path relative to the directory or its base name, and a
+	// matching subdirectory is skipped with everything in it. Excluded
+	// files are still parsed for the input structs of their package, but
+	// their annotations are skipped.
+	Exclude []string
+
+	// excludeRoot is the directory walked for input files, which the
+	// patterns of Exclude are relative to.
+	excludeRoot string
+}
+
+// Routers an adapter can be generated for with Options.Router.
+const (
+	// RouterChi generates Register<Receiver>Chi functions for github.com/go-chi/chi/v5.
+	RouterChi = "chi"
+	// RouterGin generates Register<Receiver>Gin functions for github.com/gin-gonic/gin.
+	RouterGin = "gin"
+)
+
+// Envelope shapes of success responses, selected with Options.Envelope.
+const (
+	// EnvelopeResponse wraps results as {"error": "", "response": <result>}.
+	EnvelopeResponse = "response"
+	// EnvelopeData wraps results as {"data": <result>}.
+	EnvelopeData = "data"
+	// EnvelopeBare writes results as they are.
+	EnvelopeBare = "bare"
+)
+
+// envelopeShape is the shape of responses selected by Options: the
+// Envelope and the keys of error messages and of wrapped results.
+// ErrorsKey, the key of the messages collected with
+// Options.CollectErrors, is ErrorKey with an s appended. ResponseKey is
+// empty for EnvelopeBare.
+type envelopeShape struct {
+	Name        string
+	ErrorKey    string
+	ErrorsKey   string
+	ResponseKey string
+}
+
+// envelopeKeyRe matches the keys accepted by Options.ErrorKey and
+// Options.ResponseKey, which are also used as XML element names.
+var envelopeKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// envelopeFor returns the envelope shape selected by opts, with the
+// defaults of empty options filled in.
+func envelopeFor(opts Options) (envelopeShape, error) {
+	envelope := envelopeShape{Name: opts.Envelope, ErrorKey: "error"}
+	switch envelope.Name {
+	case "":
+		envelope.Name = EnvelopeResponse
+	case EnvelopeResponse, EnvelopeData, EnvelopeBare:
+	default:
+		return envelopeShape{}, fmt.Errorf("unsupported envelope %q", opts.Envelope)
+	}
+	if envelope.Name != EnvelopeBare {
+		envelope.ResponseKey = envelope.Name
+	}
+
+	if opts.ErrorKey != "" {
+		envelope.ErrorKey = opts.ErrorKey
+	}
+	if opts.ResponseKey != "" {
+		if envelope.Name == EnvelopeBare {
+			return envelopeShape{}, fmt.Errorf("response key cannot be used with the %s envelope", EnvelopeBare)
+		}
+		envelope.ResponseKey = opts.ResponseKey
+	}
+	for _, key := range []string{envelope.ErrorKey, envelope.ResponseKey} {
+		if key != "" && !envelopeKeyRe.MatchString(key) {
+			return envelopeShape{}, fmt.Errorf("invalid envelope key %q", key)
+		}
+	}
+	if envelope.ErrorKey == envelope.ResponseKey {
+		return envelopeShape{}, fmt.Errorf("error and response keys must differ, got %q for both", envelope.ErrorKey)
+	}
+	envelope.ErrorsKey = envelope.ErrorKey + "s"
+	if envelope.ErrorsKey == envelope.ResponseKey {
+		return envelopeShape{}, fmt.Errorf("collected errors and response keys must differ, got %q for both", envelope.ErrorsKey)
+	}
+	return envelope, nil
+}
+
+// HealthzURL is the URL of the liveness endpoint generated with Options.Healthz.
+const HealthzURL = "/healthz"
+
 // Generate parses the input file, extracts API method information,
 // and generates handler code based on the parsed information.
+// If outputFile is StdoutPath, the generated code is written to os.Stdout.
 func Generate(inputFile, outputFile string) error {
-	// Parse the input file
-	methods, err := parseFile(inputFile)
+	return GenerateWithOptions(inputFile, outputFile, Options{})
+}
+
+// GenerateWithOptions is like Generate but allows customizing the output with opts.
+func GenerateWithOptions(inputFile, outputFile string, opts Options) error {
+	return GeneratePackage([]string{inputFile}, outputFile, opts)
+}
+
+// GeneratePackage parses the input files as a single package and writes
+// handler code for the API methods of all of them into one output file.
+// Input structs may be declared in any of the input files.
+func GeneratePackage(inputFiles []string, outputFile string, opts Options) error {
+	_, err := GeneratePackageChanged(inputFiles, outputFile, opts)
+	return err
+}
+
+// GeneratePackageChanged is like GeneratePackage but also reports whether
+// the output file was written. It is not if it already holds the generated
+// code, so its modification time is kept and builds depending on it are not
+// triggered again.
+func GeneratePackageChanged(inputFiles []string, outputFile string, opts Options) (bool, error) {
+	if len(inputFiles) == 0 {
+		return false, fmt.Errorf("no input files")
+	}
+
+	if outputFile == StdoutPath {
+		return true, generateTo(inputFiles, os.Stdout, opts)
+	}
+
+	var buf bytes.Buffer
+	err := generateTo(inputFiles, &buf, opts)
+	if err != nil {
+		return false, err
+	}
+
+	// Write the formatted code to the output file
+	return writeFileChanged(outputFile, buf.Bytes())
+}
+
+// generateTo generates handler code for the input files and writes
+// the formatted result to w.
+func generateTo(inputFiles []string, w io.Writer, opts Options) error {
+	pkg, code, err := generateCode(inputFiles, opts)
 	if err != nil {
 		return err
 	}
 
-	// Get the package name from the input file
-	packageName, err := getPackageName(inputFile)
+	err = writeExtras(pkg, opts)
 	if err != nil {
 		return err
 	}
 
-	// Group methods by receiver type
+	_, err = w.Write(code)
+	return err
+}
+
+// writeExtras writes the outputs other than the handlers that opts asks
+// for: the OpenAPI spec, Postman collection, JSON Schemas, client and
+// mocks of pkg.
+func writeExtras(pkg *parsedPackage, opts Options) error {
+	methods := pkg.Methods
+
+	if opts.OpenAPIFile != "" {
+		envelope, err := envelopeFor(opts)
+		if err != nil {
+			return err
+		}
+		err = writeOpenAPIFile(opts.OpenAPIFile, outputPackageName(pkg.Name, opts), methods, envelope)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.PostmanFile != "" {
+		err := writePostmanFile(opts.PostmanFile, outputPackageName(pkg.Name, opts), methods)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.JSONSchemaDir != "" {
+		err := writeJSONSchemas(opts.JSONSchemaDir, methods)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.ClientFile != "" {
+		clientCode, err := renderClient(pkg.Name, methods, opts.ClientFile, opts)
+		if err != nil {
+			return err
+		}
+		err = writeFile(opts.ClientFile, clientCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.MocksFile != "" {
+		mockCode, err := render(mockTemplate, pkg.Name, methods, opts)
+		if err != nil {
+			return err
+		}
+		err = writeFile(opts.MocksFile, mockCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateCode parses the input files and returns the parsed package
+// along with its formatted handler code.
+func generateCode(inputFiles []string, opts Options) (*parsedPackage, []byte, error) {
+	pkg, err := parseFiles(inputFiles, opts.Lax)
+	if err != nil {
+		return nil, nil, err
+	}
+	warn(opts, pkg)
+
+	tmpl, err := loadHandlerTemplate(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	code, err := render(tmpl, pkg.Name, pkg.Methods, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pkg, code, nil
+}
+
+// render executes tmpl for the given methods of package packageName
+// and returns the formatted code.
+func render(tmpl *template.Template, packageName string, methods []Method, opts Options) ([]byte, error) {
+	return renderShared(tmpl, packageName, methods, opts, true)
+}
+
+// renderShared is like render, but only includes the declarations shared
+// by all receivers, such as ErrorStatuses, if shared is set.
+func renderShared(tmpl *template.Template, packageName string, methods []Method, opts Options, shared bool) ([]byte, error) {
+	packageName = outputPackageName(packageName, opts)
+	if !token.IsIdentifier(packageName) {
+		return nil, fmt.Errorf("invalid package name %q", packageName)
+	}
+
+	switch opts.Router {
+	case "", RouterChi, RouterGin:
+	default:
+		return nil, fmt.Errorf("unsupported router %q", opts.Router)
+	}
+	for _, method := range opts.CORSMethods {
+		if !isToken(method) {
+			return nil, fmt.Errorf("invalid CORS method %q", method)
+		}
+	}
+	for _, header := range opts.CORSHeaders {
+		if !isToken(header) {
+			return nil, fmt.Errorf("invalid CORS header %q", header)
+		}
+	}
+
+	envelope, err := envelopeFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// encoding/xml cannot encode maps
+	if opts.XML {
+		for _, method := range methods {
+			if strings.HasPrefix(method.OutputType, "map[") {
+				return nil, fmt.Errorf("method %s: result type %s cannot be written as XML", method.Name, method.OutputResult())
+			}
+		}
+	}
+
+	// Group methods by receiver type. The templates range over the groups
+	// in sorted receiver order, and the methods of a receiver keep their
+	// declaration order, which is also the order URLs with parameters are
+	// matched in, so the output is the same on every run.
 	groupedMethods := make(map[string][]Method)
 	for _, method := range methods {
 		groupedMethods[method.ReceiverType] = append(groupedMethods[method.ReceiverType], method)
@@ -31,40 +443,219 @@ func Generate(inputFile, outputFile string) error {
 
 	// Prepare data for template
 	data := struct {
-		PackageName string
-		Methods     map[string][]Method
+		PackageName        string
+		Methods            map[string][]Method
+		Imports            []ImportSpec
+		StrictMethods      bool
+		NoRecover          bool
+		Logging            bool
+		Metrics            bool
+		CORSOrigin         string
+		CORSMethods        []string
+		CORSHeaders        []string
+		CollectErrors      bool
+		Router             string
+		IntrospectURL      string
+		HealthzURL         string
+		Shared             bool
+		HideInternalErrors bool
+		Envelope           string
+		ErrorKey           string
+		ErrorsKey          string
+		ResponseKey        string
+		XML                bool
+		Gzip               bool
+		Slog               bool
+		RequestID          bool
+		Otel               bool
 	}{
-		PackageName: packageName,
-		Methods:     groupedMethods,
+		PackageName:        packageName,
+		Methods:            groupedMethods,
+		StrictMethods:      opts.StrictMethods,
+		NoRecover:          opts.NoRecover,
+		Logging:            opts.Logging,
+		Metrics:            opts.Metrics,
+		CORSOrigin:         opts.CORSOrigin,
+		CORSMethods:        opts.CORSMethods,
+		CORSHeaders:        opts.CORSHeaders,
+		CollectErrors:      opts.CollectErrors,
+		Router:             opts.Router,
+		Imports:            inputImports(methods),
+		Shared:             shared,
+		HideInternalErrors: opts.HideInternalErrors,
+		Envelope:           envelope.Name,
+		ErrorKey:           envelope.ErrorKey,
+		ErrorsKey:          envelope.ErrorsKey,
+		ResponseKey:        envelope.ResponseKey,
+		XML:                opts.XML,
+		Gzip:               opts.Gzip,
+		Slog:               opts.Slog,
+		RequestID:          opts.RequestID,
+		Otel:               opts.Otel,
+	}
+	if opts.Introspect {
+		data.IntrospectURL = IntrospectURL
+	}
+	if opts.Healthz {
+		data.HealthzURL = HealthzURL
 	}
 
-	// Generate handler code using the template
+	// Generate code using the template
 	var buf bytes.Buffer
-	err = handlerTemplate.Execute(&buf, data)
+	buf.WriteString(generatedHeader(methods))
+	err = tmpl.Execute(&buf, data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Format the generated code
-	formattedCode, err := format.Source(buf.Bytes())
+	// Fix the imports of the generated code and format it
+	return formatSource(buf.Bytes())
+}
+
+// generatedHeader returns the comment marking code generated from the
+// files methods are declared in as generated, followed by a blank line.
+// It matches the ^// Code generated .* DO NOT EDIT\.$ convention of go generate.
+// If some of the files have build constraints, they are followed by a
+// //go:build line requiring all of them, so the generated code only builds
+// along with the methods it calls.
+func generatedHeader(methods []Method) string {
+	return generatedComment(methods) + buildConstraintLine(methods)
+}
+
+// generatedComment returns the Code generated comment of generatedHeader.
+func generatedComment(methods []Method) string {
+	var sources []string
+	seen := make(map[string]bool)
+	for _, method := range methods {
+		source := filepath.Base(method.File)
+		if !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return "// Code generated by gonerator; DO NOT EDIT.\n\n"
+	}
+	return "// Code generated by gonerator from " + strings.Join(sources, ", ") + "; DO NOT EDIT.\n\n"
+}
+
+// buildConstraintLine returns the //go:build line of generatedHeader,
+// followed by a blank line, or "" if no method has a build constraint.
+func buildConstraintLine(methods []Method) string {
+	var expr constraint.Expr
+	seen := make(map[string]bool)
+	for _, method := range methods {
+		if method.BuildConstraint == "" || seen[method.BuildConstraint] {
+			continue
+		}
+		seen[method.BuildConstraint] = true
+		other, err := constraint.Parse("//go:build " + method.BuildConstraint)
+		if err != nil {
+			continue
+		}
+		if expr == nil {
+			expr = other
+		} else {
+			expr = &constraint.AndExpr{X: expr, Y: other}
+		}
+	}
+	if expr == nil {
+		return ""
+	}
+	return "//go:build " + expr.String() + "\n\n"
+}
+
+// writeOpenAPIFile writes the OpenAPI spec for methods to outputFile.
+func writeOpenAPIFile(outputFile, title string, methods []Method, envelope envelopeShape) error {
+	var buf bytes.Buffer
+	err := writeOpenAPI(&buf, title, methods, envelope)
 	if err != nil {
 		return err
 	}
 
-	// Write the formatted code to the output file
-	err = os.WriteFile(outputFile, formattedCode, 0644)
+	return writeFile(outputFile, buf.Bytes())
+}
+
+// writePostmanFile writes the Postman collection for methods to outputFile.
+func writePostmanFile(outputFile, name string, methods []Method) error {
+	var buf bytes.Buffer
+	err := writePostman(&buf, name, methods)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return writeFile(outputFile, buf.Bytes())
 }
 
-func getPackageName(filename string) (string, error) {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filename, nil, parser.PackageClauseOnly)
+// inputImports returns the imports of the input types of methods
+// declared in other packages.
+func inputImports(methods []Method) []ImportSpec {
+	var imports []ImportSpec
+	seen := make(map[ImportSpec]bool)
+	for _, method := range methods {
+		if method.InputImport.Path != "" && !seen[method.InputImport] {
+			seen[method.InputImport] = true
+			imports = append(imports, method.InputImport)
+		}
+	}
+	return imports
+}
+
+// anyMethod reports whether any of methods satisfies pred.
+func anyMethod(methods []Method, pred func(Method) bool) bool {
+	for _, method := range methods {
+		if pred(method) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile writes data to the file at path, creating its directory
+// and any missing parents first. The file is left untouched if it
+// already holds data.
+func writeFile(path string, data []byte) error {
+	_, err := writeFileChanged(path, data)
+	return err
+}
+
+// writeFileChanged is like writeFile but also reports whether the file
+// was written.
+func writeFileChanged(path string, data []byte) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
 	if err != nil {
-		return "", err
+		return false, fmt.Errorf("cannot create output directory: %w", err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isToken reports whether s is an HTTP token, such as a method or a
+// header name.
+func isToken(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r)
+	})
+}
+
+// MocksPath returns the _mock.go path next to outputFile.
+func MocksPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + "_mock.go"
+}
+
+// outputPackageName returns the package name of the generated code:
+// opts.PackageName if set and packageName of the parsed input otherwise.
+func outputPackageName(packageName string, opts Options) string {
+	if opts.PackageName != "" {
+		return opts.PackageName
 	}
-	return node.Name.Name, nil
+	return packageName
 }
@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stdPackages maps the names of the standard library packages the
+// generated code may refer to to their import paths.
+var stdPackages = map[string]string{
+	"bytes":   "bytes",
+	"context": "context",
+	"errors":  "errors",
+	"fmt":     "fmt",
+	"gzip":    "compress/gzip",
+	"http":    "net/http",
+	"io":      "io",
+	"json":    "encoding/json",
+	"log":     "log",
+	"mail":    "net/mail",
+	"os":      "os",
+	"rand":    "crypto/rand",
+	"regexp":  "regexp",
+	"strconv": "strconv",
+	"strings": "strings",
+	"subtle":  "crypto/subtle",
+	"slog":    "log/slog",
+	"sync":    "sync",
+	"time":    "time",
+	"url":     "net/url",
+	"xml":     "encoding/xml",
+}
+
+// importSpec is an import of the generated code.
+type importSpec struct {
+	name, path string
+}
+
+// formatSource fixes the imports of the generated code src and formats it.
+func formatSource(src []byte) ([]byte, error) {
+	src, err := fixImports(src)
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(src)
+}
+
+// fixImports adds the imports of the standard library packages src refers
+// to but does not import and removes the unused ones, like goimports. Other
+// imports are kept as they are, since their package names are not known.
+func fixImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identifiers that are not declared in the file may be package names
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	stdNames := make(map[string]string)
+	for name, path := range stdPackages {
+		stdNames[path] = name
+	}
+
+	var imports []importSpec
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		imp := importSpec{path: path}
+		name, std := stdNames[path]
+		if spec.Name != nil {
+			imp.name = spec.Name.Name
+			name, std = imp.name, true
+		}
+		if std && name != "_" && name != "." && !used[name] {
+			continue
+		}
+		imports = append(imports, imp)
+		imported[name] = true
+	}
+	for name := range used {
+		if path, ok := stdPackages[name]; ok && !imported[name] {
+			imports = append(imports, importSpec{path: path})
+		}
+	}
+
+	// Replace the import declarations, which precede all other declarations
+	start := fset.Position(file.Name.End()).Offset
+	end := start
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if end == start {
+				start = fset.Position(gen.Pos()).Offset
+			}
+			end = fset.Position(gen.End()).Offset
+		}
+	}
+
+	var out strings.Builder
+	out.Write(src[:start])
+	if start == end {
+		out.WriteString("\n\n")
+	}
+	out.WriteString(importDecl(imports))
+	out.Write(src[end:])
+	return []byte(out.String()), nil
+}
+
+// importDecl returns an import declaration of imports with the standard
+// library packages and the other packages in separate sorted groups.
+func importDecl(imports []importSpec) string {
+	if len(imports) == 0 {
+		return ""
+	}
+
+	sort.Slice(imports, func(i, j int) bool {
+		iStd, jStd := isStdPath(imports[i].path), isStdPath(imports[j].path)
+		if iStd != jStd {
+			return iStd
+		}
+		return imports[i].path < imports[j].path
+	})
+
+	var decl strings.Builder
+	decl.WriteString("import (\n")
+	for i, imp := range imports {
+		if i > 0 && isStdPath(imp.path) != isStdPath(imports[i-1].path) {
+			decl.WriteString("\n")
+		}
+		if imp.name != "" {
+			decl.WriteString(imp.name + " ")
+		}
+		decl.WriteString(strconv.Quote(imp.path) + "\n")
+	}
+	decl.WriteString(")")
+	return decl.String()
+}
+
+// isStdPath reports whether path is the import path of a standard library
+// package, which unlike other import paths has no dot in its first element.
+func isStdPath(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// IntrospectURL is the URL the description of the API methods of a
+// receiver is served at when generating with Options.Introspect.
+const IntrospectURL = "/_introspect"
+
+// introspection describes the API methods of a receiver.
+type introspection struct {
+	Methods []introspectionMethod `json:"methods"`
+}
+
+type introspectionMethod struct {
+	Name        string               `json:"name"`
+	Url         string               `json:"url"`
+	HTTPMethods []string             `json:"http_methods"`
+	Auth        bool                 `json:"auth"`
+	Params      []introspectionParam `json:"params"`
+}
+
+type introspectionParam struct {
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Type         string   `json:"type"`
+	Required     bool     `json:"required,omitempty"`
+	Min          *float64 `json:"min,omitempty"`
+	Max          *float64 `json:"max,omitempty"`
+	MinExclusive bool     `json:"min_exclusive,omitempty"`
+	MaxExclusive bool     `json:"max_exclusive,omitempty"`
+	MultipleOf   *int     `json:"multiple_of,omitempty"`
+	Enum         []string `json:"enum,omitempty"`
+	Default      string   `json:"default,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+	Email        bool     `json:"email,omitempty"`
+	UUID         bool     `json:"uuid,omitempty"`
+	DateLayout   string   `json:"datetime,omitempty"`
+}
+
+// introspectJSON returns a Go string literal holding the JSON description
+// of methods, which are the API methods of one receiver.
+func introspectJSON(methods []Method) (string, error) {
+	doc := introspection{Methods: []introspectionMethod{}}
+	for _, method := range methods {
+		m := introspectionMethod{
+			Name:        method.Name,
+			Url:         method.ApiMethod.Url,
+			HTTPMethods: httpMethods(method.ApiMethod.Method),
+			Auth:        method.ApiMethod.Auth,
+			Params:      []introspectionParam{},
+		}
+		for _, field := range method.StructFields {
+			m.Params = append(m.Params, introspectionParam{
+				Name:         field.ParamName(),
+				Aliases:      field.Tag.Aliases,
+				Type:         field.Type,
+				Required:     field.Tag.Required,
+				Min:          field.Tag.MinFloat,
+				Max:          field.Tag.MaxFloat,
+				MinExclusive: field.Tag.MinExclusive,
+				MaxExclusive: field.Tag.MaxExclusive,
+				MultipleOf:   field.Tag.MultipleOf,
+				Enum:         field.Tag.Enum,
+				Default:      field.Tag.Default,
+				Regex:        field.Tag.Regex,
+				Email:        field.Tag.Email,
+				UUID:         field.Tag.UUID,
+				DateLayout:   field.TimeLayout(),
+			})
+		}
+		doc.Methods = append(doc.Methods, m)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(doc)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Quote(buf.String()), nil
+}
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// jsonSchemaDialect is the JSON Schema version of the generated documents.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is a JSON Schema document or subschema.
+type jsonSchema struct {
+	Schema     string                `json:"$schema,omitempty"`
+	Title      string                `json:"title,omitempty"`
+	Type       string                `json:"type"`
+	Format     string                `json:"format,omitempty"`
+	Properties map[string]jsonSchema `json:"properties,omitempty"`
+	Required   []string              `json:"required,omitempty"`
+	Enum       []interface{}         `json:"enum,omitempty"`
+	Default    interface{}           `json:"default,omitempty"`
+	Pattern    string                `json:"pattern,omitempty"`
+	Minimum    *float64              `json:"minimum,omitempty"`
+	Maximum    *float64              `json:"maximum,omitempty"`
+	// ExclusiveMinimum and ExclusiveMaximum are numbers since draft 6
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *int     `json:"multipleOf,omitempty"`
+	MinLength        *int     `json:"minLength,omitempty"`
+	MaxLength        *int     `json:"maxLength,omitempty"`
+}
+
+// JSONSchemaPath returns the path of the JSON Schema of inputType in dir.
+func JSONSchemaPath(dir, inputType string) string {
+	return filepath.Join(dir, inputType+".schema.json")
+}
+
+// writeJSONSchemas writes a JSON Schema document for the input type of
+// every method to dir. Input types shared by several methods are written once.
+func writeJSONSchemas(dir string, methods []Method) error {
+	written := make(map[string]bool)
+	for _, method := range methods {
+		if written[method.InputType] {
+			continue
+		}
+		written[method.InputType] = true
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(inputJSONSchema(method))
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(JSONSchemaPath(dir, method.InputType), buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inputJSONSchema describes the parameters of method as a JSON object.
+func inputJSONSchema(method Method) jsonSchema {
+	schema := jsonSchema{
+		Schema:     jsonSchemaDialect,
+		Title:      method.InputType,
+		Type:       "object",
+		Properties: make(map[string]jsonSchema),
+	}
+	for _, field := range method.StructFields {
+		schema.Properties[field.ParamName()] = fieldJSONSchema(field)
+		if field.Tag.Required {
+			schema.Required = append(schema.Required, field.ParamName())
+		}
+	}
+	return schema
+}
+
+// fieldJSONSchema translates the type and validation rules of field.
+func fieldJSONSchema(field StructField) jsonSchema {
+	schema := jsonSchema{
+		Enum:    jsonSchemaEnum(field),
+		Pattern: field.Tag.Regex,
+	}
+
+	switch {
+	case field.IsInteger(), field.IsFloat():
+		schema.Type = "number"
+		if field.IsInteger() {
+			schema.Type = "integer"
+		}
+		if field.Tag.MinExclusive {
+			schema.ExclusiveMinimum = field.Tag.MinFloat
+		} else {
+			schema.Minimum = field.Tag.MinFloat
+		}
+		if field.Tag.MaxExclusive {
+			schema.ExclusiveMaximum = field.Tag.MaxFloat
+		} else {
+			schema.Maximum = field.Tag.MaxFloat
+		}
+		schema.MultipleOf = field.Tag.MultipleOf
+	case field.IsBool():
+		schema.Type = "boolean"
+	case field.IsString():
+		schema.Type = "string"
+		schema.MinLength, schema.MaxLength = lengthBounds(field.Tag)
+		if field.Tag.Email {
+			schema.Format = "email"
+		}
+		if field.Tag.UUID {
+			schema.Format = "uuid"
+		}
+		if field.Tag.DateLayout != "" {
+			schema.Format = dateFormat(field)
+		}
+	case field.IsTime():
+		schema.Type = "string"
+		schema.Format = dateFormat(field)
+	}
+
+	if field.Tag.Default != "" {
+		schema.Default = jsonSchemaValue(field, field.Tag.Default)
+	}
+
+	return schema
+}
+
+// lengthBounds returns the inclusive bounds of the length of a string
+// field with the rules of tag.
+func lengthBounds(tag ApiValidatorTag) (minLength, maxLength *int) {
+	minLength, maxLength = tag.Min, tag.Max
+	if minLength != nil && tag.MinExclusive {
+		n := *minLength + 1
+		minLength = &n
+	}
+	if maxLength != nil && tag.MaxExclusive {
+		n := *maxLength - 1
+		maxLength = &n
+	}
+	return minLength, maxLength
+}
+
+// jsonSchemaEnum converts the enum values of field to its JSON type.
+func jsonSchemaEnum(field StructField) []interface{} {
+	if len(field.Tag.Enum) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(field.Tag.Enum))
+	for _, value := range field.Tag.Enum {
+		values = append(values, jsonSchemaValue(field, value))
+	}
+	return values
+}
+
+// jsonSchemaValue converts value, a default or enum value of field, to
+// its JSON type. The value has already been checked to parse as the field type.
+func jsonSchemaValue(field StructField, value string) interface{} {
+	switch {
+	case field.IsUnsigned():
+		u, _ := strconv.ParseUint(value, 10, field.IntBits())
+		return u
+	case field.IsInteger():
+		i, _ := strconv.ParseInt(value, 10, field.IntBits())
+		return i
+	case field.IsFloat():
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	case field.IsBool():
+		switch strings.ToLower(value) {
+		case "on":
+			return true
+		case "off":
+			return false
+		}
+		b, _ := strconv.ParseBool(value)
+		return b
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// lintWarning is a suspicious but valid annotation of a method declared
+// in File.
+type lintWarning struct {
+	File string
+	Text string
+}
+
+// LintPackage parses inputFiles like GeneratePackage and returns warnings
+// about annotations that are valid but likely mistakes, formatted as
+// "file:line: message". Nothing is written. Of opts, only Lax and Warn are used.
+func LintPackage(inputFiles []string, opts Options) ([]string, error) {
+	if len(inputFiles) == 0 {
+		return nil, fmt.Errorf("no input files")
+	}
+
+	pkg, err := parseFiles(inputFiles, opts.Lax)
+	if err != nil {
+		return nil, err
+	}
+	warn(opts, pkg)
+
+	var warnings []string
+	for _, warning := range lintPackage(pkg) {
+		warnings = append(warnings, warning.Text)
+	}
+	return warnings, nil
+}
+
+// LintFiles is like LintPackage for the inputs of GenerateFiles: every
+// input file is parsed with the other files of its package, and only the
+// warnings about methods of inputFiles are returned.
+func LintFiles(inputFiles []string, opts Options) ([]string, error) {
+	var warnings []string
+	parsed := make(map[string]map[string]*parsedPackage)
+	for _, inputFile := range inputFiles {
+		dir := filepath.Dir(inputFile)
+		if _, ok := parsed[dir]; !ok {
+			packages, err := parseDir(dir, opts)
+			if err != nil {
+				return nil, err
+			}
+			warnDir(opts, packages)
+			parsed[dir] = packages
+		}
+		for _, pkg := range parsed[dir] {
+			for _, warning := range lintPackage(pkg) {
+				if filepath.Clean(warning.File) == filepath.Clean(inputFile) {
+					warnings = append(warnings, warning.Text)
+				}
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// LintDir is like GenerateDirWithOptions but returns the warnings about
+// the source files of dir like LintFiles. Of opts, only Lax, Warn and
+// Exclude are used.
+func LintDir(dir string, opts Options) ([]string, error) {
+	inputFiles, opts, err := dirInputs(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return LintFiles(inputFiles, opts)
+}
+
+// lintPackage returns the warnings about the methods of pkg.
+func lintPackage(pkg *parsedPackage) []lintWarning {
+	var warnings []lintWarning
+	for i, method := range pkg.Methods {
+		annotation := pkg.annotations[i]
+		for _, text := range lintMethod(pkg.Fset, annotation.comment, annotation.config, method) {
+			warnings = append(warnings, lintWarning{File: method.File, Text: text})
+		}
+	}
+	return warnings
+}
+
+// lintMethod returns the warnings about method, parsed from the
+// apigen:api annotation starting at comment and holding config.
+func lintMethod(fset *token.FileSet, comment *ast.Comment, config string, method Method) []string {
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		position := fset.Position(comment.Pos())
+		warnings = append(warnings, fmt.Sprintf("%s:%d: method %s: ", position.Filename, position.Line, method.Name)+fmt.Sprintf(format, args...))
+	}
+
+	var options map[string]json.RawMessage
+	if err := decodeConfig(config, &options); err == nil {
+		if _, ok := options["auth_env_key"]; method.ApiMethod.Auth && !ok {
+			warn("auth is set without auth_env_key, the key is read from %s", method.ApiMethod.AuthEnvKey)
+		}
+	}
+
+	validated := false
+	for _, field := range method.StructFields {
+		validated = validated || isValidated(field.Tag)
+	}
+	if len(method.StructFields) > 0 && !validated {
+		warn("input type %s has no validated fields", method.InputType)
+	}
+
+	for _, field := range method.StructFields {
+		if field.Tag.Required && field.Tag.Default != "" {
+			warn("field %s: default %q is never used as the field is required", field.Name, field.Tag.Default)
+		}
+		seen := make(map[string]bool)
+		for _, value := range field.Tag.Enum {
+			key := value
+			if field.Tag.EnumCI {
+				key = strings.ToLower(value)
+			}
+			if seen[key] {
+				warn("field %s: enum value %q is listed more than once", field.Name, value)
+			}
+			seen[key] = true
+		}
+	}
+
+	return warnings
+}
+
+// isValidated reports whether tag has a rule that rejects some values.
+func isValidated(tag ApiValidatorTag) bool {
+	return tag.Required || tag.RequiredIf != nil ||
+		tag.Min != nil || tag.Max != nil || tag.MinFloat != nil || tag.MaxFloat != nil || tag.MultipleOf != nil ||
+		len(tag.Enum) > 0 || tag.Regex != "" || tag.Email || tag.UUID || tag.DateLayout != "" || tag.Custom != ""
+}
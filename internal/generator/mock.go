@@ -0,0 +1,55 @@
+package generator
+
+import "text/template"
+
+var mockTemplate = template.Must(template.New("mock").Parse(`
+package {{.PackageName}}
+
+import (
+    {{range .Imports}}
+    {{.Name}} "{{.Path}}"
+    {{end}}
+)
+
+{{range $receiverType, $methods := .Methods}}
+// {{$receiverType}}Interface is the set of API methods implemented by {{$receiverType}}.
+type {{$receiverType}}Interface interface {
+    {{range $methods}}
+    {{.Name}}({{if .WithContext}}ctx context.Context, {{end}}in {{.InputParam}}{{if .WithRequest}}, r *http.Request{{end}}) ({{.OutputResult}}, error)
+    {{end}}
+}
+
+var (
+    _ {{$receiverType}}Interface = (*{{$receiverType}})(nil)
+    _ {{$receiverType}}Interface = (*{{$receiverType}}Mock)(nil)
+)
+
+// {{$receiverType}}Mock is a mock implementation of {{$receiverType}}Interface.
+// Every call is recorded in the <Method>Calls field. A call returns the result
+// of <Method>Func if it is set, and <Method>Result and <Method>Err otherwise.
+type {{$receiverType}}Mock struct {
+    mu sync.Mutex
+    {{range $methods}}
+    {{.Name}}Calls  []{{.InputParam}}
+    {{.Name}}Func   func({{if .WithContext}}ctx context.Context, {{end}}in {{.InputParam}}{{if .WithRequest}}, r *http.Request{{end}}) ({{.OutputResult}}, error)
+    {{.Name}}Result {{.OutputResult}}
+    {{.Name}}Err    error
+    {{end}}
+}
+
+{{range $methods}}
+// {{.Name}} records the call and returns the programmed result.
+func (m *{{$receiverType}}Mock) {{.Name}}({{if .WithContext}}ctx context.Context, {{end}}in {{.InputParam}}{{if .WithRequest}}, r *http.Request{{end}}) ({{.OutputResult}}, error) {
+    m.mu.Lock()
+    m.{{.Name}}Calls = append(m.{{.Name}}Calls, in)
+    fn, res, err := m.{{.Name}}Func, m.{{.Name}}Result, m.{{.Name}}Err
+    m.mu.Unlock()
+
+    if fn != nil {
+        return fn({{if .WithContext}}ctx, {{end}}in{{if .WithRequest}}, r{{end}})
+    }
+    return res, err
+}
+{{end}}
+{{end}}
+`))
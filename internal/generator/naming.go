@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// splitWords splits an identifier into its words. Words are separated by
+// underscores, hyphens and spaces, and by case changes, keeping acronyms
+// together: "FullName", "full_name" and "full-name" are [Full Name], and
+// "HTTPStatus" is [HTTP Status].
+func splitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		lowerToUpper := unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev))
+		acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// capitalize returns the lowercased word with its first letter in upper case.
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// toPascal returns s in PascalCase: "full_name" is "FullName" and
+// "HTTPStatus" is "HttpStatus".
+func toPascal(s string) string {
+	var b strings.Builder
+	for _, word := range splitWords(s) {
+		b.WriteString(capitalize(word))
+	}
+	return b.String()
+}
+
+// toCamel returns s in camelCase: "FullName" and "full_name" are "fullName".
+func toCamel(s string) string {
+	words := splitWords(s)
+	if len(words) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToLower(words[0]))
+	for _, word := range words[1:] {
+		b.WriteString(capitalize(word))
+	}
+	return b.String()
+}
+
+// toSnake returns s in snake_case: "FullName" is "full_name", "Login" is
+// "login" and "HTTPStatus" is "http_status".
+func toSnake(s string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "_")
+}
+
+// jsonTag returns the request parameter name of field, which is also its
+// key in JSON request bodies. See StructField.ParamName.
+func jsonTag(field StructField) string {
+	return field.ParamName()
+}
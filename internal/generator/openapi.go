@@ -0,0 +1,294 @@
+package generator
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPIDoc is the root of an OpenAPI 3.0 document.
+type openAPIDoc struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       openAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components openAPIComponents                      `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required,omitempty"`
+	Schema   openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Type       string                   `json:"type,omitempty"`
+	Format     string                   `json:"format,omitempty"`
+	Properties map[string]openAPISchema `json:"properties,omitempty"`
+	Required   []string                 `json:"required,omitempty"`
+	Enum       []interface{}            `json:"enum,omitempty"`
+	Default    string                   `json:"default,omitempty"`
+	Pattern    string                   `json:"pattern,omitempty"`
+	Minimum    *float64                 `json:"minimum,omitempty"`
+	Maximum    *float64                 `json:"maximum,omitempty"`
+	// ExclusiveMinimum and ExclusiveMaximum are booleans in OpenAPI 3.0
+	ExclusiveMinimum bool           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool           `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *int           `json:"multipleOf,omitempty"`
+	MinLength        *int           `json:"minLength,omitempty"`
+	MaxLength        *int           `json:"maxLength,omitempty"`
+	Items            *openAPISchema `json:"items,omitempty"`
+}
+
+type openAPIComponents struct {
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// openAPISecurity returns the name and the security scheme of the auth
+// of method in the spec, e.g. XAuth for an X-Auth header.
+func openAPISecurity(method ApiMethod) (string, openAPISecurityScheme) {
+	if method.AuthScheme == AuthSchemeBearer {
+		return "Bearer", openAPISecurityScheme{Type: "http", Scheme: AuthSchemeBearer}
+	}
+	return strings.ReplaceAll(method.AuthHeader, "-", ""), openAPISecurityScheme{Type: "apiKey", In: "header", Name: method.AuthHeader}
+}
+
+// writeOpenAPI writes an OpenAPI 3.0 document describing methods to w.
+// Responses are described in the given envelope shape.
+func writeOpenAPI(w io.Writer, title string, methods []Method, envelope envelopeShape) error {
+	doc := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   title,
+			Version: "1.0.0",
+		},
+		Paths: make(map[string]map[string]openAPIOperation),
+	}
+
+	for _, method := range methods {
+		if doc.Paths[method.ApiMethod.Url] == nil {
+			doc.Paths[method.ApiMethod.Url] = make(map[string]openAPIOperation)
+		}
+
+		for _, httpMethod := range strings.Split(method.ApiMethod.Method, ",") {
+			httpMethod = strings.TrimSpace(httpMethod)
+			doc.Paths[method.ApiMethod.Url][strings.ToLower(httpMethod)] = openAPIOperationFor(method, httpMethod, envelope)
+		}
+
+		if method.ApiMethod.Auth {
+			if doc.Components.SecuritySchemes == nil {
+				doc.Components.SecuritySchemes = make(map[string]openAPISecurityScheme)
+			}
+			name, scheme := openAPISecurity(method.ApiMethod)
+			doc.Components.SecuritySchemes[name] = scheme
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// openAPIEnvelopeSchema describes a success response in the envelope shape.
+func openAPIEnvelopeSchema(envelope envelopeShape) openAPISchema {
+	switch envelope.Name {
+	case EnvelopeData:
+		return openAPISchema{
+			Type: "object",
+			Properties: map[string]openAPISchema{
+				envelope.ResponseKey: {Type: "object"},
+			},
+		}
+	case EnvelopeBare:
+		return openAPISchema{Type: "object"}
+	}
+	return openAPISchema{
+		Type: "object",
+		Properties: map[string]openAPISchema{
+			envelope.ErrorKey:    {Type: "string"},
+			envelope.ResponseKey: {Type: "object"},
+		},
+	}
+}
+
+// openAPIOperationFor describes method when called with httpMethod.
+// GET and DELETE parameters are described as query parameters, any
+// other method takes them as a form-encoded or JSON request body.
+func openAPIOperationFor(method Method, httpMethod string, envelope envelopeShape) openAPIOperation {
+	op := openAPIOperation{
+		OperationID: method.ReceiverType + method.Name + httpMethod[:1] + strings.ToLower(httpMethod[1:]),
+		Responses: map[string]openAPIResponse{
+			"default": {
+				Description: "Error",
+				Content: map[string]openAPIMediaType{
+					"application/json": {Schema: openAPISchema{
+						Type: "object",
+						Properties: map[string]openAPISchema{
+							envelope.ErrorKey:  {Type: "string"},
+							envelope.ErrorsKey: {Type: "array", Items: &openAPISchema{Type: "string"}},
+							"code":             {Type: "string"},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	success := openAPIResponse{Description: http.StatusText(method.ApiMethod.SuccessStatus)}
+	if method.ApiMethod.SuccessStatus != http.StatusNoContent {
+		success.Content = map[string]openAPIMediaType{
+			"application/json": {Schema: openAPIEnvelopeSchema(envelope)},
+		}
+	}
+	op.Responses[strconv.Itoa(method.ApiMethod.SuccessStatus)] = success
+
+	if method.ApiMethod.Auth {
+		name, _ := openAPISecurity(method.ApiMethod)
+		op.Security = []map[string][]string{{name: {}}}
+	}
+
+	var fields []StructField
+	for _, field := range method.StructFields {
+		if slices.Contains(method.ApiMethod.PathParams(), field.ParamName()) {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:     field.ParamName(),
+				In:       "path",
+				Required: true,
+				Schema:   openAPIFieldSchema(field),
+			})
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	if httpMethod == "GET" || httpMethod == "DELETE" {
+		for _, field := range fields {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:     field.ParamName(),
+				In:       "query",
+				Required: field.Tag.Required,
+				Schema:   openAPIFieldSchema(field),
+			})
+		}
+		return op
+	}
+
+	body := openAPISchema{
+		Type:       "object",
+		Properties: make(map[string]openAPISchema),
+	}
+	for _, field := range fields {
+		body.Properties[field.ParamName()] = openAPIFieldSchema(field)
+		if field.Tag.Required {
+			body.Required = append(body.Required, field.ParamName())
+		}
+	}
+	op.RequestBody = &openAPIRequestBody{
+		Required: len(body.Required) > 0,
+		Content: map[string]openAPIMediaType{
+			"application/x-www-form-urlencoded": {Schema: body},
+			"application/json":                  {Schema: body},
+		},
+	}
+
+	return op
+}
+
+// openAPIFieldSchema describes the type and validation rules of field.
+func openAPIFieldSchema(field StructField) openAPISchema {
+	schema := openAPISchema{
+		Enum:    jsonSchemaEnum(field),
+		Default: field.Tag.Default,
+		Pattern: field.Tag.Regex,
+	}
+
+	switch {
+	case field.IsInteger():
+		schema.Type = "integer"
+		schema.Minimum = field.Tag.MinFloat
+		schema.Maximum = field.Tag.MaxFloat
+		schema.ExclusiveMinimum = field.Tag.MinExclusive
+		schema.ExclusiveMaximum = field.Tag.MaxExclusive
+		schema.MultipleOf = field.Tag.MultipleOf
+	case field.IsFloat():
+		schema.Type = "number"
+		schema.Format = "double"
+		if field.FloatBits() == 32 {
+			schema.Format = "float"
+		}
+		schema.Minimum = field.Tag.MinFloat
+		schema.Maximum = field.Tag.MaxFloat
+		schema.ExclusiveMinimum = field.Tag.MinExclusive
+		schema.ExclusiveMaximum = field.Tag.MaxExclusive
+	case field.IsBool():
+		schema.Type = "boolean"
+	case field.IsString():
+		schema.Type = "string"
+		schema.MinLength, schema.MaxLength = lengthBounds(field.Tag)
+		if field.Tag.Email {
+			schema.Format = "email"
+		}
+		if field.Tag.UUID {
+			schema.Format = "uuid"
+		}
+		if field.Tag.DateLayout != "" {
+			schema.Format = dateFormat(field)
+		}
+	case field.IsTime():
+		schema.Type = "string"
+		schema.Format = dateFormat(field)
+	}
+
+	return schema
+}
+
+// dateFormat returns the format of a date field: "date" or "date-time"
+// if its layout is the one of RFC 3339 dates or timestamps, and no format
+// otherwise.
+func dateFormat(field StructField) string {
+	switch field.TimeLayout() {
+	case time.DateOnly:
+		return "date"
+	case time.RFC3339:
+		return "date-time"
+	}
+	return ""
+}
@@ -2,12 +2,27 @@
 package generator
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/build"
+	"go/build/constraint"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ApiMethod represents the API method configuration extracted from comments.
@@ -16,16 +31,121 @@ type ApiMethod struct {
 	Auth       bool   `json:"auth"`
 	Method     string `json:"method"`
 	AuthEnvKey string `json:"auth_env_key"`
+	AuthHeader string `json:"auth_header"`
+	AuthScheme string `json:"auth_scheme"`
+	TimeoutMs  int    `json:"timeout_ms"`
+
+	// MaxBodyBytes limits the size of the request body.
+	// It defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+
+	// CollectErrors makes the handler validate all parameters and return
+	// the messages of all that are invalid, rather than only the first.
+	CollectErrors bool `json:"collect_errors"`
+
+	// ErrorCode is the code of the validation errors of fields without
+	// a code rule. It defaults to DefaultErrorCode.
+	ErrorCode string `json:"error_code"`
+
+	// SuccessStatus is the 2xx status of successful responses.
+	// It defaults to 200.
+	SuccessStatus int `json:"success_status"`
+}
+
+// DefaultErrorCode is the code of validation errors if neither the field
+// nor the method sets one.
+const DefaultErrorCode = "VALIDATION_ERROR"
+
+// PathSegment is a segment of the URL of an API method. A parameter
+// segment like {id} matches any non-empty segment and passes it to the
+// request parameter named Value.
+type PathSegment struct {
+	Value string
+	Param bool
+}
+
+// PathSegments splits the URL into its slash-separated segments.
+// As the URL starts with a slash, the first segment is empty.
+func (m ApiMethod) PathSegments() []PathSegment {
+	var segments []PathSegment
+	for _, part := range strings.Split(m.Url, "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			segments = append(segments, PathSegment{Value: part[1 : len(part)-1], Param: true})
+		} else {
+			segments = append(segments, PathSegment{Value: part})
+		}
+	}
+	return segments
 }
 
+// PathParams returns the names of the parameters in the URL.
+func (m ApiMethod) PathParams() []string {
+	var params []string
+	for _, segment := range m.PathSegments() {
+		if segment.Param {
+			params = append(params, segment.Value)
+		}
+	}
+	return params
+}
+
+// DefaultMaxBodyBytes is the request body size limit of API methods
+// that do not set max_body_bytes.
+const DefaultMaxBodyBytes = 1 << 20
+
+// AuthSchemeBearer is the auth scheme of methods that read the key from
+// an "Authorization: Bearer <key>" header.
+const AuthSchemeBearer = "bearer"
+
 // ApiValidatorTag represents the validation rules for API parameters.
 type ApiValidatorTag struct {
-	Required  bool
-	Min       *int
-	Max       *int
-	ParamName string
-	Enum      []string
-	Default   string
+	Required     bool
+	Min          *int
+	Max          *int
+	MinFloat     *float64
+	MaxFloat     *float64
+	MinExclusive bool
+	MaxExclusive bool
+	MultipleOf   *int
+	ParamName    string
+	Aliases      []string
+	Enum         []string
+	EnumCI       bool
+	Default      string
+	Regex        string
+	Email        bool
+	UUID         bool
+	DateLayout   string
+	Trim         bool
+	Message      string
+	Code         string
+	Custom       string
+	RequiredIf   *RequiredIf
+}
+
+// MinOp returns the operator values must satisfy against the lower bound:
+// ">" for min_ex and ">=" for min.
+func (t ApiValidatorTag) MinOp() string {
+	if t.MinExclusive {
+		return ">"
+	}
+	return ">="
+}
+
+// MaxOp returns the operator values must satisfy against the upper bound:
+// "<" for max_ex and "<=" for max.
+func (t ApiValidatorTag) MaxOp() string {
+	if t.MaxExclusive {
+		return "<"
+	}
+	return "<="
+}
+
+// RequiredIf is a required_if rule: the field is required if the
+// request parameter Param has the value Value.
+type RequiredIf struct {
+	Param string
+	Value string
 }
 
 // StructField represents a field in the input struct for an API method.
@@ -35,61 +155,523 @@ type StructField struct {
 	Tag  ApiValidatorTag
 }
 
+// ParamName returns the request parameter name of the field.
+// It is the paramname rule if set and the field name in snake_case otherwise,
+// the way the json tags of result types are usually written: Login is login
+// and FullName is full_name.
+func (f StructField) ParamName() string {
+	if f.Tag.ParamName != "" {
+		return f.Tag.ParamName
+	}
+	return toSnake(f.Name)
+}
+
+// IsInteger reports whether the field has a signed or unsigned integer type.
+// Min and Max of integer fields bound the parsed value.
+func (f StructField) IsInteger() bool {
+	switch f.Type {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
+// IsUnsigned reports whether the field has an unsigned integer type.
+func (f StructField) IsUnsigned() bool {
+	return f.IsInteger() && strings.HasPrefix(f.Type, "u")
+}
+
+// IntBits returns the bit size of an integer field as strconv expects it:
+// 0 for int and uint, whose size depends on the platform.
+func (f StructField) IntBits() int {
+	bits, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(f.Type, "u"), "int"))
+	return bits
+}
+
+// IsFloat reports whether the field has a floating-point type.
+// MinFloat and MaxFloat of float fields bound the parsed value.
+func (f StructField) IsFloat() bool {
+	return f.Type == "float64" || f.Type == "float32"
+}
+
+// FloatBits returns the bit size of a float field.
+func (f StructField) FloatBits() int {
+	if f.Type == "float32" {
+		return 32
+	}
+	return 64
+}
+
+// IsBool reports whether the field has a bool type.
+func (f StructField) IsBool() bool {
+	return f.Type == "bool"
+}
+
+// IsString reports whether the field has a string type.
+// Min and Max of string fields bound the length of the value.
+func (f StructField) IsString() bool {
+	return f.Type == "string"
+}
+
+// IsTime reports whether the field has a time.Time type.
+// Values of time fields are parsed with TimeLayout.
+func (f StructField) IsTime() bool {
+	return f.Type == "time.Time"
+}
+
+// TimeLayout returns the layout values of the field are parsed with: the
+// datetime layout of its tag, or time.RFC3339 for time fields without one.
+func (f StructField) TimeLayout() string {
+	if f.Tag.DateLayout == "" && f.IsTime() {
+		return time.RFC3339
+	}
+	return f.Tag.DateLayout
+}
+
+// ImportSpec represents an import of the generated code.
+type ImportSpec struct {
+	Name string
+	Path string
+}
+
 // Method represents a parsed API method with all its metadata.
+// WithContext is set for methods taking a context.Context as first parameter,
+// and WithRequest for methods taking the *http.Request after the input.
+// BuildConstraint is the //go:build expression of File, if it has one.
 type Method struct {
-	Name         string
-	ReceiverName string
-	ReceiverType string
-	InputType    string
-	OutputType   string
-	ApiMethod    ApiMethod
-	StructFields []StructField
+	Name            string
+	ReceiverName    string
+	ReceiverType    string
+	InputType       string
+	InputPointer    bool
+	InputImport     ImportSpec
+	WithContext     bool
+	WithRequest     bool
+	OutputType      string
+	OutputPointer   bool
+	ApiMethod       ApiMethod
+	StructFields    []StructField
+	File            string
+	BuildConstraint string
+}
+
+// InputParam returns the input parameter type as declared by the method.
+func (m Method) InputParam() string {
+	if m.InputPointer {
+		return "*" + m.InputType
+	}
+	return m.InputType
+}
+
+// OutputResult returns the first result type as declared by the method.
+func (m Method) OutputResult() string {
+	if m.OutputPointer {
+		return "*" + m.OutputType
+	}
+	return m.OutputType
+}
+
+// ErrorCode returns the code of the validation errors of field: its code
+// rule, the error_code of the method or DefaultErrorCode.
+func (m Method) ErrorCode(field StructField) string {
+	if field.Tag.Code != "" {
+		return field.Tag.Code
+	}
+	if m.ApiMethod.ErrorCode != "" {
+		return m.ApiMethod.ErrorCode
+	}
+	return DefaultErrorCode
+}
+
+// Field returns the field of the input struct with the request
+// parameter name paramName, or nil if there is none.
+func (m Method) Field(paramName string) *StructField {
+	for i := range m.StructFields {
+		if m.StructFields[i].ParamName() == paramName {
+			return &m.StructFields[i]
+		}
+	}
+	return nil
+}
+
+// parsedPackage holds the API methods parsed from the files of one package.
+// Fset is the file set the files were parsed with, so positions of the
+// parsed declarations can be reported.
+type parsedPackage struct {
+	Fset    *token.FileSet
+	Name    string
+	Methods []Method
+
+	// Warnings are the problems that did not fail parsing, such as the
+	// methods generated without parameters with Options.Lax
+	Warnings []string
+
+	// annotations are the apigen:api annotations of Methods, in the same
+	// order, kept for linting
+	annotations []annotation
+}
+
+// warn passes the warnings of pkg to opts.Warn.
+func warn(opts Options, pkg *parsedPackage) {
+	if opts.Warn == nil {
+		return
+	}
+	for _, warning := range pkg.Warnings {
+		opts.Warn(warning)
+	}
+}
+
+// warnDir is like warn for the packages of a directory, in sorted order.
+func warnDir(opts Options, packages map[string]*parsedPackage) {
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		warn(opts, packages[name])
+	}
+}
+
+// annotation is an apigen:api comment and the config it holds.
+type annotation struct {
+	comment *ast.Comment
+	config  string
+}
+
+// ParseMethods parses the Go source file filename and returns its API
+// methods, with their ApiMethod config and the StructFields of their input
+// types, as the generator sees them. Nothing is written, so callers can
+// build their own generators or documentation from the result.
+func ParseMethods(filename string) ([]Method, error) {
+	pkg, err := parseFiles([]string{filename}, false)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Methods, nil
 }
 
-// parseFile parses the given Go source file and extracts API method information.
-func parseFile(filename string) ([]Method, error) {
+// parseFiles parses the given Go source files of a single package and extracts
+// API method information from all of them. Input structs are looked up across
+// all files, so they may be declared in a different file than their methods.
+// Every file is parsed exactly once. A file named StdinPath is read from
+// standard input and reported as stdinName. See parseNodes for lax.
+func parseFiles(filenames []string, lax bool) (*parsedPackage, error) {
 	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	names := make([]string, 0, len(filenames))
+	nodes := make([]*ast.File, 0, len(filenames))
+	for _, filename := range filenames {
+		src, err := readSource(filename)
+		if err != nil {
+			return nil, err
+		}
+		if filename == StdinPath {
+			filename = stdinName
+		}
+
+		node, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, filename)
+		nodes = append(nodes, node)
+	}
+
+	return parseNodes(fset, names, nodes, nil, lax)
+}
+
+// utf8BOM is the byte order mark some Windows editors start files with.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// readSource returns the contents of filename, or of standard input for
+// StdinPath, without a leading UTF-8 BOM and with CRLF line endings
+// converted to LF, so annotations and tags of files written on Windows
+// parse like those of any other file.
+func readSource(filename string) ([]byte, error) {
+	var src []byte
+	var err error
+	if filename == StdinPath {
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		src, err = os.ReadFile(filename)
+	}
 	if err != nil {
 		return nil, err
 	}
+	src = bytes.TrimPrefix(src, utf8BOM)
+	return bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n")), nil
+}
 
-	var methods []Method
+// parseDir parses every non-test Go source file in dir once and returns
+// the API methods of each package declared in dir, keyed by package name.
+// Files excluded by opts only provide the input structs of their package:
+// their annotations are skipped, and they are ignored if they do not parse.
+// See parseNodes for opts.Lax.
+func parseDir(dir string, opts Options) (map[string]*parsedPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, decl := range node.Decls {
-		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-			if funcDecl.Doc != nil {
-				for _, comment := range funcDecl.Doc.List {
-					if strings.HasPrefix(comment.Text, "// apigen:api") {
-						method, err := parseMethod(funcDecl, comment.Text, filename)
-						if err != nil {
-							return nil, err
-						}
-						methods = append(methods, method)
-						break
+	fset := token.NewFileSet()
+	filenames := make(map[string][]string)
+	nodes := make(map[string][]*ast.File)
+	excluded := make(map[string]bool)
+	for _, entry := range entries {
+		filename := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !isSourceFile(filename) {
+			continue
+		}
+		excluded[filename] = isExcludedFile(filename, opts)
+		src, err := readSource(filename)
+		if err != nil {
+			return nil, err
+		}
+		node, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if err != nil && excluded[filename] {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		filenames[node.Name.Name] = append(filenames[node.Name.Name], filename)
+		nodes[node.Name.Name] = append(nodes[node.Name.Name], node)
+	}
+
+	// Parse the packages in sorted order so errors are reported deterministically
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	packages := make(map[string]*parsedPackage)
+	for _, name := range names {
+		pkg, err := parseNodes(fset, filenames[name], nodes[name], excluded, opts.Lax)
+		if err != nil {
+			return nil, err
+		}
+		packages[name] = pkg
+	}
+
+	return packages, nil
+}
+
+// parseNodes extracts API method information from the parsed files of a single package.
+// The annotations of the files in excluded are skipped, but their structs
+// are still used as input types.
+// A method whose input type is not found is an error, unless lax is set, in
+// which case the error is one of the warnings of the package and the method
+// is parsed without fields.
+func parseNodes(fset *token.FileSet, filenames []string, nodes []*ast.File, excluded map[string]bool, lax bool) (*parsedPackage, error) {
+	pkg := &parsedPackage{Fset: fset}
+	if len(nodes) > 0 {
+		pkg.Name = nodes[0].Name.Name
+	}
+
+	structs := collectStructs(nodes)
+
+	for i, node := range nodes {
+		if excluded[filenames[i]] {
+			continue
+		}
+		buildConstraint, err := fileBuildConstraint(fset, node)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range node.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+				if comment, config, ok := apiConfig(funcDecl.Doc); ok {
+					method, err := parseMethod(fset, funcDecl, comment, config, structs, fileImports(node), filepath.Dir(filenames[i]))
+					var missing *missingInputError
+					if lax && errors.As(err, &missing) {
+						pkg.Warnings = append(pkg.Warnings, err.Error())
+					} else if err != nil {
+						return nil, err
 					}
+					method.File = filenames[i]
+					method.BuildConstraint = buildConstraint
+					pkg.Methods = append(pkg.Methods, method)
+					pkg.annotations = append(pkg.annotations, annotation{comment: comment, config: config})
 				}
 			}
 		}
 	}
 
-	return methods, nil
+	err := checkRoutes(pkg.Methods)
+	if err != nil {
+		return nil, err
+	}
+
+	return pkg, nil
 }
 
-// parseMethod extracts method information from an AST function declaration.
-func parseMethod(funcDecl *ast.FuncDecl, comment, filename string) (Method, error) {
+// checkRoutes returns an error if two methods of the same receiver serve
+// the same URL, even with different HTTP methods, as the handlers dispatch
+// on the URL alone and one would shadow the other. URLs that only differ
+// in the names of their parameters are the same route.
+func checkRoutes(methods []Method) error {
+	seen := make(map[string]string)
+	for _, method := range methods {
+		var parts []string
+		for _, segment := range method.ApiMethod.PathSegments() {
+			if segment.Param {
+				parts = append(parts, "{}")
+			} else {
+				parts = append(parts, segment.Value)
+			}
+		}
+		path := strings.Join(parts, "/")
+
+		key := method.ReceiverType + " " + path
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("duplicate route %s on %s: methods %s and %s must use different URLs", method.ApiMethod.Url, method.ReceiverType, other, method.Name)
+		}
+		seen[key] = method.Name
+	}
+	return nil
+}
+
+// fileBuildConstraint returns the expression of the //go:build line of
+// node, or "" if it has none.
+func fileBuildConstraint(fset *token.FileSet, node *ast.File) (string, error) {
+	for _, group := range node.Comments {
+		if group.Pos() >= node.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				return "", errorAt(fset, comment.Pos(), "invalid build constraint: %w", err)
+			}
+			return expr.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// collectStructs builds a lookup table of all struct types declared in nodes.
+func collectStructs(nodes []*ast.File) map[string]*ast.StructType {
+	structs := make(map[string]*ast.StructType)
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if typeSpec, ok := n.(*ast.TypeSpec); ok {
+				if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+					structs[typeSpec.Name.Name] = structType
+				}
+			}
+			return true
+		})
+	}
+	return structs
+}
+
+// missingInputError is the error of a method whose input type is not a
+// struct declared in the parsed files.
+type missingInputError struct {
+	err error
+}
+
+func (e *missingInputError) Error() string {
+	return e.err.Error()
+}
+
+// apiConfig returns the first comment of the apigen:api annotation in doc
+// and the config it holds. The config may continue on consecutive
+// "// apigen:api" lines, which are joined, or span a /* apigen:api */ block.
+// The config of a /* apigen:api+yaml */ block is returned with yamlPrefix.
+func apiConfig(doc *ast.CommentGroup) (*ast.Comment, string, bool) {
+	if doc == nil {
+		return nil, "", false
+	}
+	for i, comment := range doc.List {
+		if config, ok := strings.CutPrefix(comment.Text, "/* apigen:api+yaml"); ok {
+			return comment, yamlPrefix + strings.TrimSuffix(config, "*/"), true
+		}
+		if config, ok := strings.CutPrefix(comment.Text, "/* apigen:api"); ok {
+			return comment, strings.TrimSuffix(config, "*/"), true
+		}
+		config, ok := strings.CutPrefix(comment.Text, "// apigen:api")
+		if !ok {
+			continue
+		}
+		for _, next := range doc.List[i+1:] {
+			line, ok := strings.CutPrefix(next.Text, "// apigen:api")
+			if !ok {
+				break
+			}
+			config += "\n" + line
+		}
+		return comment, config, true
+	}
+	return nil, "", false
+}
+
+// parseMethod extracts method information from an AST function declaration
+// annotated with comment holding config. Input structs are looked up in
+// structs, or for qualified input types in the package imported under that
+// name in imports, resolved from dir. Errors are prefixed with the
+// file:line of the offending declaration. If the input type is not found,
+// the method is parsed without fields and returned with a *missingInputError.
+func parseMethod(fset *token.FileSet, funcDecl *ast.FuncDecl, comment *ast.Comment, config string, structs map[string]*ast.StructType, imports map[string]string, dir string) (Method, error) {
+	if funcDecl.Recv == nil {
+		return Method{}, errorAt(fset, funcDecl.Pos(), "method %s: apigen:api must annotate a method", funcDecl.Name.Name)
+	}
+	receiverName, receiverType, err := parseReceiver(funcDecl.Recv.List[0])
+	if err != nil {
+		return Method{}, errorAt(fset, funcDecl.Pos(), "method %s: %w", funcDecl.Name.Name, err)
+	}
+
+	params, results, withContext, err := checkSignature(funcDecl.Type, imports)
+	if err != nil {
+		return Method{}, errorAt(fset, funcDecl.Pos(), "method %s: unsupported signature: %w", funcDecl.Name.Name, err)
+	}
+
+	inputPackage, inputName, inputPointer, err := parseInputType(params[0])
+	if err != nil {
+		return Method{}, errorAt(fset, funcDecl.Pos(), "method %s: %w", funcDecl.Name.Name, err)
+	}
+	outputType, outputPointer, err := parseOutputType(results[0])
+	if err != nil {
+		return Method{}, errorAt(fset, funcDecl.Pos(), "method %s: %w", funcDecl.Name.Name, err)
+	}
+
 	method := Method{
-		Name:         funcDecl.Name.Name,
-		ReceiverName: funcDecl.Recv.List[0].Names[0].Name,
-		ReceiverType: funcDecl.Recv.List[0].Type.(*ast.StarExpr).X.(*ast.Ident).Name,
-		InputType:    funcDecl.Type.Params.List[1].Type.(*ast.Ident).Name,
-		OutputType:   funcDecl.Type.Results.List[0].Type.(*ast.StarExpr).X.(*ast.Ident).Name,
+		Name:          funcDecl.Name.Name,
+		ReceiverName:  receiverName,
+		ReceiverType:  receiverType,
+		InputType:     inputName,
+		InputPointer:  inputPointer,
+		WithContext:   withContext,
+		WithRequest:   len(params) == 2,
+		OutputType:    outputType,
+		OutputPointer: outputPointer,
+	}
+
+	inputStructs := structs
+	if inputPackage != "" {
+		path, ok := imports[inputPackage]
+		if !ok {
+			return Method{}, errorAt(fset, funcDecl.Pos(), "method %s: package %s of input type %s.%s is not imported", funcDecl.Name.Name, inputPackage, inputPackage, inputName)
+		}
+		inputStructs, err = packageStructs(fset, path, dir)
+		if err != nil {
+			return Method{}, errorAt(fset, funcDecl.Pos(), "method %s: %w", funcDecl.Name.Name, err)
+		}
+		method.InputType = inputPackage + "." + inputName
+		method.InputImport = ImportSpec{Name: inputPackage, Path: path}
+	}
+	var missingInput error
+	if _, ok := inputStructs[inputName]; !ok {
+		missingInput = &missingInputError{errorAt(fset, funcDecl.Pos(), "input type %s not found for method %s", method.InputType, funcDecl.Name.Name)}
 	}
 
 	apiMethod := ApiMethod{}
-	err := json.Unmarshal([]byte(strings.TrimPrefix(comment, "// apigen:api")), &apiMethod)
+	err = decodeConfig(config, &apiMethod)
 	if err != nil {
-		return Method{}, err
+		return Method{}, errorAt(fset, comment.Pos(), "method %s: invalid apigen:api config: %w", funcDecl.Name.Name, err)
 	}
 	method.ApiMethod = apiMethod
 
@@ -103,63 +685,495 @@ func parseMethod(funcDecl *ast.FuncDecl, comment, filename string) (Method, erro
 		method.ApiMethod.AuthEnvKey = "API_AUTH_KEY"
 	}
 
-	structFields, err := parseStructFields(filename, method.InputType)
+	switch method.ApiMethod.AuthScheme {
+	case "":
+	case AuthSchemeBearer:
+		if method.ApiMethod.AuthHeader == "" {
+			method.ApiMethod.AuthHeader = "Authorization"
+		}
+	default:
+		return Method{}, errorAt(fset, comment.Pos(), "method %s: unsupported auth_scheme %q", funcDecl.Name.Name, method.ApiMethod.AuthScheme)
+	}
+
+	if method.ApiMethod.TimeoutMs < 0 {
+		return Method{}, errorAt(fset, comment.Pos(), "method %s: timeout_ms must be >= 0, got %d", funcDecl.Name.Name, method.ApiMethod.TimeoutMs)
+	}
+	if method.ApiMethod.TimeoutMs > 0 && !method.WithContext {
+		return Method{}, errorAt(fset, comment.Pos(), "method %s: timeout_ms requires a context.Context parameter", funcDecl.Name.Name)
+	}
+
+	// Set default body size limit if not specified
+	if method.ApiMethod.MaxBodyBytes < 0 {
+		return Method{}, errorAt(fset, comment.Pos(), "method %s: max_body_bytes must be >= 0, got %d", funcDecl.Name.Name, method.ApiMethod.MaxBodyBytes)
+	}
+	if method.ApiMethod.MaxBodyBytes == 0 {
+		method.ApiMethod.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	if method.ApiMethod.SuccessStatus == 0 {
+		method.ApiMethod.SuccessStatus = http.StatusOK
+	}
+	if method.ApiMethod.SuccessStatus < 200 || method.ApiMethod.SuccessStatus > 299 {
+		return Method{}, errorAt(fset, comment.Pos(), "method %s: success_status must be a 2xx status, got %d", funcDecl.Name.Name, method.ApiMethod.SuccessStatus)
+	}
+
+	// Set default auth header to X-Auth if not specified
+	if method.ApiMethod.AuthHeader == "" {
+		method.ApiMethod.AuthHeader = "X-Auth"
+	}
+
+	method.StructFields, err = parseStructFields(fset, inputStructs, inputName)
 	if err != nil {
 		return Method{}, err
 	}
-	method.StructFields = structFields
 
-	return method, nil
+	for _, param := range method.ApiMethod.PathParams() {
+		if missingInput != nil {
+			// Path parameters cannot be passed without fields, even if lax
+			return Method{}, missingInput.(*missingInputError).err
+		}
+		if method.Field(param) == nil {
+			return Method{}, errorAt(fset, comment.Pos(), "method %s: path parameter %s of %s does not match any field of %s", funcDecl.Name.Name, param, method.ApiMethod.Url, method.InputType)
+		}
+	}
+
+	paramNames := make(map[string]string)
+	for _, field := range method.StructFields {
+		paramNames[field.ParamName()] = field.Name
+	}
+	for _, field := range method.StructFields {
+		for _, alias := range field.Tag.Aliases {
+			if other, ok := paramNames[alias]; ok {
+				return Method{}, errorAt(fset, comment.Pos(), "method %s: alias %s of %s.%s collides with the parameter of %s.%s", funcDecl.Name.Name, alias, method.InputType, field.Name, method.InputType, other)
+			}
+			paramNames[alias] = field.Name
+		}
+	}
+
+	for _, field := range method.StructFields {
+		requiredIf := field.Tag.RequiredIf
+		if requiredIf == nil {
+			continue
+		}
+		other := method.Field(requiredIf.Param)
+		if other == nil {
+			return Method{}, errorAt(fset, comment.Pos(), "method %s: required_if of %s.%s refers to unknown parameter %s", funcDecl.Name.Name, method.InputType, field.Name, requiredIf.Param)
+		}
+		if err := checkValue(*other, requiredIf.Value); err != nil {
+			return Method{}, errorAt(fset, comment.Pos(), "method %s: required_if of %s.%s: %s must be %s, got %q", funcDecl.Name.Name, method.InputType, field.Name, requiredIf.Param, other.Type, requiredIf.Value)
+		}
+		if _, err := requiredIfCond(method, field); err != nil {
+			return Method{}, errorAt(fset, comment.Pos(), "method %s: required_if of %s.%s: %v", funcDecl.Name.Name, method.InputType, field.Name, err)
+		}
+	}
+
+	return method, missingInput
 }
 
-// parseStructFields extracts field information from the input struct of an API method.
-func parseStructFields(filename string, structName string) ([]StructField, error) {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+// parseReceiver returns the name and type name of a method receiver.
+// Both pointer and value receivers are supported.
+func parseReceiver(recv *ast.Field) (string, string, error) {
+	var name string
+	if len(recv.Names) > 0 {
+		name = recv.Names[0].Name
+	}
+
+	typ := recv.Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return "", "", fmt.Errorf("unsupported receiver type %s", types.ExprString(recv.Type))
+	}
+
+	return name, ident.Name, nil
+}
+
+// checkSignature checks that an API method has the shape
+// func([context.Context,] Params[, *http.Request]) (Result, error) and
+// returns the types of its parameters, without the context, and results,
+// and whether the method takes a context. imports are the imports of the
+// file declaring the method, by name, used to tell context.Context from
+// other types named Context.
+func checkSignature(funcType *ast.FuncType, imports map[string]string) ([]ast.Expr, []ast.Expr, bool, error) {
+	params := fieldTypes(funcType.Params)
+	results := fieldTypes(funcType.Results)
+	signature := types.ExprString(funcType)
+
+	withContext := false
+	if len(params) > 0 {
+		if ctx, ok := params[0].(*ast.SelectorExpr); ok && ctx.Sel.Name == "Context" {
+			pkg, ok := ctx.X.(*ast.Ident)
+			withContext = ok && imports[pkg.Name] == "context"
+		}
+	}
+	if withContext {
+		params = params[1:]
+		if len(params) != 1 && len(params) != 2 {
+			return nil, nil, false, fmt.Errorf("expected 2 or 3 parameters, got %d in %s", len(params)+1, signature)
+		}
+		if len(params) == 2 && types.ExprString(params[1]) != "*http.Request" {
+			return nil, nil, false, fmt.Errorf("third parameter must be *http.Request in %s", signature)
+		}
+	} else {
+		if len(params) != 1 && len(params) != 2 {
+			return nil, nil, false, fmt.Errorf("expected 1 or 2 parameters without context.Context, got %d in %s", len(params), signature)
+		}
+		if len(params) == 2 && types.ExprString(params[1]) != "*http.Request" {
+			return nil, nil, false, fmt.Errorf("second parameter must be *http.Request in %s", signature)
+		}
+	}
+
+	if len(results) != 2 {
+		return nil, nil, false, fmt.Errorf("expected 2 results, got %d in %s", len(results), signature)
+	}
+	if ident, ok := results[1].(*ast.Ident); !ok || ident.Name != "error" {
+		return nil, nil, false, fmt.Errorf("second result must be error in %s", signature)
+	}
+
+	return params, results, withContext, nil
+}
+
+// fieldTypes returns the type of every entry of fields,
+// repeating the type of fields declaring several names.
+func fieldTypes(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+
+	var result []ast.Expr
+	for _, field := range fields.List {
+		n := max(len(field.Names), 1)
+		for i := 0; i < n; i++ {
+			result = append(result, field.Type)
+		}
+	}
+	return result
+}
+
+// parseInputType returns the package qualifier, the type name, and whether
+// the input parameter of an API method is a pointer. The input type must be
+// a struct name, optionally qualified by a package and optionally a pointer.
+func parseInputType(expr ast.Expr) (string, string, bool, error) {
+	typ := expr
+	star, pointer := typ.(*ast.StarExpr)
+	if pointer {
+		typ = star.X
+	}
+
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return "", t.Name, pointer, nil
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			return pkg.Name, t.Sel.Name, pointer, nil
+		}
+	}
+
+	return "", "", false, fmt.Errorf("unsupported input type %s", types.ExprString(expr))
+}
+
+// parseOutputType returns the type of the first result of an API method,
+// without the leading * of a pointer, and whether it is a pointer. The
+// result must be a type of this package or a builtin type, a pointer to
+// one, or a slice or map of such types, so it can be written as JSON.
+func parseOutputType(expr ast.Expr) (string, bool, error) {
+	typ := expr
+	star, pointer := typ.(*ast.StarExpr)
+	if pointer {
+		typ = star.X
+	}
+
+	if pointer && !isOutputElem(typ, false) || !pointer && !isOutputElem(typ, true) {
+		return "", false, fmt.Errorf("unsupported result type %s", types.ExprString(expr))
+	}
+	return types.ExprString(typ), pointer, nil
+}
+
+// isOutputElem reports whether expr is a type name of this package, or,
+// if composite is set, a pointer, slice or map whose elements are such
+// types or composites themselves.
+func isOutputElem(expr ast.Expr, composite bool) bool {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.StarExpr:
+		return composite && isOutputElem(t.X, false)
+	case *ast.ArrayType:
+		return composite && t.Len == nil && isOutputElem(t.Elt, true)
+	case *ast.MapType:
+		return composite && isOutputElem(t.Key, false) && isOutputElem(t.Value, true)
+	}
+	return false
+}
+
+// fileImports returns the import paths of node keyed by the name they are used under.
+// Without an explicit name, the last element of the import path is assumed.
+func fileImports(node *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range node.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := pathpkg.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// packageStructs parses the package with the given import path,
+// resolved from dir, into fset and returns the struct types declared in it.
+func packageStructs(fset *token.FileSet, importPath, dir string) (map[string]*ast.StructType, error) {
+	pkg, err := build.Import(importPath, dir, 0)
 	if err != nil {
 		return nil, err
 	}
 
+	var nodes []*ast.File
+	for _, name := range pkg.GoFiles {
+		filename := filepath.Join(pkg.Dir, name)
+		src, err := readSource(filename)
+		if err != nil {
+			return nil, err
+		}
+		node, err := parser.ParseFile(fset, filename, src, 0)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return collectStructs(nodes), nil
+}
+
+// parseStructFields extracts field information from the input struct of an API method.
+// Fields of embedded structs are flattened into the result, as they are
+// promoted to the input struct.
+func parseStructFields(fset *token.FileSet, structs map[string]*ast.StructType, structName string) ([]StructField, error) {
+	return collectStructFields(fset, structs, structName, make(map[string]string))
+}
+
+// collectStructFields appends the fields of structName, including those of
+// embedded structs, to the result. declared tracks the struct declaring each
+// field name so colliding names are reported.
+func collectStructFields(fset *token.FileSet, structs map[string]*ast.StructType, structName string, declared map[string]string) ([]StructField, error) {
+	structType, ok := structs[structName]
+	if !ok {
+		return nil, nil
+	}
+
 	var fields []StructField
 
-	ast.Inspect(node, func(n ast.Node) bool {
-		if typeSpec, ok := n.(*ast.TypeSpec); ok {
-			if typeSpec.Name.Name == structName {
-				if structType, ok := typeSpec.Type.(*ast.StructType); ok {
-					for _, field := range structType.Fields.List {
-						if len(field.Names) > 0 {
-							fieldName := field.Names[0].Name
-							fieldType := fmt.Sprintf("%s", field.Type)
-							tag := parseApiValidatorTag(field.Tag)
-							fields = append(fields, StructField{
-								Name: fieldName,
-								Type: fieldType,
-								Tag:  tag,
-							})
-						}
-					}
-				}
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			ident, ok := field.Type.(*ast.Ident)
+			if !ok || structs[ident.Name] == nil {
+				return nil, errorAt(fset, field.Pos(), "field %s.%s: embedded type must be a struct declared in the parsed files", structName, types.ExprString(field.Type))
+			}
+			embedded, err := collectStructFields(fset, structs, ident.Name, declared)
+			if err != nil {
+				return nil, err
 			}
+			fields = append(fields, embedded...)
+			continue
 		}
-		return true
-	})
+
+		fieldName := field.Names[0].Name
+		if other, ok := declared[fieldName]; ok {
+			return nil, errorAt(fset, field.Pos(), "field %s.%s: name collides with field %s.%s", structName, fieldName, other, fieldName)
+		}
+		declared[fieldName] = structName
+
+		fieldType := types.ExprString(field.Type)
+		tag, err := parseApiValidatorTag(field.Tag)
+		if err != nil {
+			return nil, errorAt(fset, field.Tag.Pos(), "field %s.%s: invalid apivalidator tag: %w", structName, fieldName, err)
+		}
+		structField := StructField{
+			Name: fieldName,
+			Type: fieldType,
+			Tag:  tag,
+		}
+		structField.Tag.Enum, err = integerEnum(structField)
+		if err != nil {
+			return nil, errorAt(fset, field.Tag.Pos(), "field %s.%s: invalid apivalidator tag: %w", structName, fieldName, err)
+		}
+		if err := checkDefault(structField); err != nil {
+			return nil, errorAt(fset, field.Tag.Pos(), "field %s.%s: invalid apivalidator tag: %w", structName, fieldName, err)
+		}
+		if err := checkBounds(structField); err != nil {
+			return nil, errorAt(fset, field.Tag.Pos(), "field %s.%s: invalid apivalidator tag: %w", structName, fieldName, err)
+		}
+		fields = append(fields, structField)
+	}
 
 	return fields, nil
 }
 
+// checkDefault reports an error if the default value of field
+// cannot be converted to the field's type or is not one of its enum values.
+func checkDefault(field StructField) error {
+	value := field.Tag.Default
+	if value == "" {
+		return nil
+	}
+
+	if err := checkValue(field, value); err != nil {
+		if field.TimeLayout() != "" {
+			return fmt.Errorf("default must be a date in the layout %s, got %q", field.TimeLayout(), value)
+		}
+		return fmt.Errorf("default must be %s, got %q", field.Type, value)
+	}
+	if len(field.Tag.Enum) > 0 && !inEnum(field, value) {
+		return fmt.Errorf("default %q must be one of [%s]", value, strings.Join(field.Tag.Enum, ", "))
+	}
+	return nil
+}
+
+// inEnum reports whether value is one of the enum values of field, compared
+// as the generated code compares them. value already parses as the field type.
+func inEnum(field StructField, value string) bool {
+	if field.IsInteger() {
+		// The enum values are formatted canonically by integerEnum
+		if field.IsUnsigned() {
+			u, _ := strconv.ParseUint(value, 10, field.IntBits())
+			value = strconv.FormatUint(u, 10)
+		} else {
+			i, _ := strconv.ParseInt(value, 10, field.IntBits())
+			value = strconv.FormatInt(i, 10)
+		}
+	}
+	for _, enumValue := range field.Tag.Enum {
+		if value == enumValue || field.Tag.EnumCI && strings.EqualFold(value, enumValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// integerEnum returns the enum values of field. The values of an integer
+// field are checked to parse as its type and formatted canonically, so they
+// can be used as keys of a map literal, and enum_ci is rejected.
+func integerEnum(field StructField) ([]string, error) {
+	if !field.IsInteger() || len(field.Tag.Enum) == 0 {
+		return field.Tag.Enum, nil
+	}
+	if field.Tag.EnumCI {
+		return nil, fmt.Errorf("enum_ci can only be used on string fields, got %s", field.Type)
+	}
+
+	values := make([]string, 0, len(field.Tag.Enum))
+	for _, value := range field.Tag.Enum {
+		if field.IsUnsigned() {
+			u, err := strconv.ParseUint(value, 10, field.IntBits())
+			if err != nil {
+				return nil, fmt.Errorf("enum values must be %s, got %q", field.Type, value)
+			}
+			value = strconv.FormatUint(u, 10)
+		} else {
+			i, err := strconv.ParseInt(value, 10, field.IntBits())
+			if err != nil {
+				return nil, fmt.Errorf("enum values must be %s, got %q", field.Type, value)
+			}
+			value = strconv.FormatInt(i, 10)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// checkValue reports an error if value does not parse as the type of field.
+func checkValue(field StructField, value string) error {
+	var err error
+	switch {
+	case field.IsUnsigned():
+		_, err = strconv.ParseUint(value, 10, field.IntBits())
+	case field.IsInteger():
+		_, err = strconv.ParseInt(value, 10, field.IntBits())
+	case field.IsFloat():
+		_, err = strconv.ParseFloat(value, field.FloatBits())
+	case field.IsBool():
+		switch strings.ToLower(value) {
+		case "on", "off":
+		default:
+			_, err = strconv.ParseBool(value)
+		}
+	case field.TimeLayout() != "":
+		_, err = time.Parse(field.TimeLayout(), value)
+	}
+	return err
+}
+
+// checkBounds reports an error if min or max of an unsigned integer field
+// is negative, as the generated comparison would not compile, or if
+// multiple_of is used on a field that is not an integer, uuid on a
+// field that is not a string or datetime on a field that is neither a
+// string nor a time.Time.
+func checkBounds(field StructField) error {
+	if field.Tag.MultipleOf != nil && !field.IsInteger() {
+		return fmt.Errorf("multiple_of can only be used on integer fields, got %s", field.Type)
+	}
+	if field.Tag.UUID && !field.IsString() {
+		return fmt.Errorf("uuid can only be used on string fields, got %s", field.Type)
+	}
+	if field.Tag.DateLayout != "" && !field.IsString() && !field.IsTime() {
+		return fmt.Errorf("datetime can only be used on string and time.Time fields, got %s", field.Type)
+	}
+	// Integer and string fields are compared with the integer bounds only
+	if !field.IsFloat() && field.Tag.MinFloat != nil && field.Tag.Min == nil {
+		return fmt.Errorf("%s must be an integer for %s, got %v", boundKey("min", field.Tag.MinExclusive), field.Type, *field.Tag.MinFloat)
+	}
+	if !field.IsFloat() && field.Tag.MaxFloat != nil && field.Tag.Max == nil {
+		return fmt.Errorf("%s must be an integer for %s, got %v", boundKey("max", field.Tag.MaxExclusive), field.Type, *field.Tag.MaxFloat)
+	}
+	if !field.IsUnsigned() {
+		return nil
+	}
+	if field.Tag.MinFloat != nil && *field.Tag.MinFloat < 0 {
+		return fmt.Errorf("%s must be >= 0 for %s, got %v", boundKey("min", field.Tag.MinExclusive), field.Type, *field.Tag.MinFloat)
+	}
+	if field.Tag.MaxFloat != nil && *field.Tag.MaxFloat < 0 {
+		return fmt.Errorf("%s must be >= 0 for %s, got %v", boundKey("max", field.Tag.MaxExclusive), field.Type, *field.Tag.MaxFloat)
+	}
+	return nil
+}
+
+// boundKey returns the tag key of a min or max bound: key itself, or
+// key with the _ex suffix if the bound is exclusive.
+func boundKey(key string, exclusive bool) string {
+	if exclusive {
+		return key + "_ex"
+	}
+	return key
+}
+
 // parseApiValidatorTag parses the apivalidator tag and extracts validation rules.
-func parseApiValidatorTag(tag *ast.BasicLit) ApiValidatorTag {
+func parseApiValidatorTag(tag *ast.BasicLit) (ApiValidatorTag, error) {
 	if tag == nil {
-		return ApiValidatorTag{}
+		return ApiValidatorTag{}, nil
 	}
 
-	tagValue := strings.Trim(tag.Value, "`")
-	apiValidatorTag := strings.TrimPrefix(tagValue, "apivalidator:")
-	apiValidatorTag = strings.Trim(apiValidatorTag, "\"")
+	tagValue, err := strconv.Unquote(tag.Value)
+	if err != nil {
+		return ApiValidatorTag{}, err
+	}
+	apiValidatorTag := reflect.StructTag(tagValue).Get("apivalidator")
 
-	parts := strings.Split(apiValidatorTag, ",")
 	result := ApiValidatorTag{}
 
+	// msg takes the rest of the tag, so the message may contain commas
+	if i := strings.Index(","+apiValidatorTag, ",msg="); i >= 0 {
+		result.Message = apiValidatorTag[i+len("msg="):]
+		apiValidatorTag = apiValidatorTag[:max(i-1, 0)]
+	}
+
+	parts := splitTagParts(apiValidatorTag)
+
 	for _, part := range parts {
 		keyValue := strings.SplitN(part, "=", 2)
 		key := keyValue[0]
@@ -173,26 +1187,111 @@ func parseApiValidatorTag(tag *ast.BasicLit) ApiValidatorTag {
 			result.Required = true
 		case "paramname":
 			result.ParamName = value
+		case "aliases":
+			result.Aliases = strings.Split(value, "|")
+			if slices.Contains(result.Aliases, "") {
+				return ApiValidatorTag{}, fmt.Errorf("aliases must be parameter names separated by |, got %q", value)
+			}
 		case "enum":
 			result.Enum = strings.Split(value, "|")
+		case "enum_ci":
+			result.Enum = strings.Split(value, "|")
+			result.EnumCI = true
 		case "default":
 			result.Default = value
-		case "min":
+		case "regex":
+			if _, err := regexp.Compile(value); err != nil {
+				return ApiValidatorTag{}, err
+			}
+			result.Regex = value
+		case "email":
+			result.Email = true
+		case "uuid":
+			result.UUID = true
+		case "datetime":
+			if value == "" {
+				return ApiValidatorTag{}, fmt.Errorf("datetime must be a time layout like 2006-01-02")
+			}
+			result.DateLayout = value
+		case "trim":
+			result.Trim = true
+		case "code":
+			result.Code = value
+		case "required_if":
+			param, otherValue, ok := strings.Cut(value, "=")
+			if !ok || param == "" {
+				return ApiValidatorTag{}, fmt.Errorf("required_if must be param=value, got %q", value)
+			}
+			result.RequiredIf = &RequiredIf{Param: param, Value: otherValue}
+		case "custom":
+			if !token.IsIdentifier(value) {
+				return ApiValidatorTag{}, fmt.Errorf("custom must be a method name, got %q", value)
+			}
+			result.Custom = value
+		case "multiple_of":
+			intValue, err := strToInt(value)
+			if err != nil || intValue <= 0 {
+				return ApiValidatorTag{}, fmt.Errorf("multiple_of must be a positive integer, got %q", value)
+			}
+			result.MultipleOf = &intValue
+		case "min", "min_ex":
+			floatValue, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return ApiValidatorTag{}, fmt.Errorf("%s must be a number, got %q", key, value)
+			}
+			if result.MinFloat != nil && result.MinExclusive != (key == "min_ex") {
+				return ApiValidatorTag{}, fmt.Errorf("min and min_ex cannot be used together")
+			}
+			result.MinFloat = &floatValue
+			result.MinExclusive = key == "min_ex"
 			if intValue, err := strToInt(value); err == nil {
 				result.Min = &intValue
 			}
-		case "max":
+		case "max", "max_ex":
+			floatValue, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return ApiValidatorTag{}, fmt.Errorf("%s must be a number, got %q", key, value)
+			}
+			if result.MaxFloat != nil && result.MaxExclusive != (key == "max_ex") {
+				return ApiValidatorTag{}, fmt.Errorf("max and max_ex cannot be used together")
+			}
+			result.MaxFloat = &floatValue
+			result.MaxExclusive = key == "max_ex"
 			if intValue, err := strToInt(value); err == nil {
 				result.Max = &intValue
 			}
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+// errorAt returns an error formatted like fmt.Errorf and prefixed with the file:line of pos.
+func errorAt(fset *token.FileSet, pos token.Pos, format string, args ...interface{}) error {
+	position := fset.Position(pos)
+	return fmt.Errorf("%s:%d: "+format, append([]interface{}{position.Filename, position.Line}, args...)...)
+}
+
+// splitTagParts splits an apivalidator tag on commas. A comma preceded by
+// a backslash is kept as part of the value, so rules like regex can contain it.
+func splitTagParts(tag string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			part.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(tag[i])
+		}
+	}
+	return append(parts, part.String())
 }
 
 func strToInt(s string) (int, error) {
-	var i int
-	_, err := fmt.Sscanf(s, "%d", &i)
-	return i, err
+	return strconv.Atoi(s)
 }
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// postmanSchema is the schema URL of Postman v2.1 collections.
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// postmanCollection is the root of a Postman v2.1 collection.
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanFolder   `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// postmanFolder groups the requests of the API methods of a receiver.
+type postmanFolder struct {
+	Name string        `json:"name"`
+	Item []postmanItem `json:"item"`
+}
+
+type postmanItem struct {
+	Name    string         `json:"name"`
+	Request postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method string         `json:"method"`
+	Header []postmanParam `json:"header"`
+	URL    postmanURL     `json:"url"`
+	Body   *postmanBody   `json:"body,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string         `json:"raw"`
+	Host     []string       `json:"host"`
+	Path     []string       `json:"path"`
+	Query    []postmanParam `json:"query,omitempty"`
+	Variable []postmanParam `json:"variable,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string         `json:"mode"`
+	URLEncoded []postmanParam `json:"urlencoded"`
+}
+
+// postmanParam is a header, query, path or body parameter. Optional
+// parameters are disabled, so they are only sent once enabled.
+type postmanParam struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+type postmanVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// writePostman writes a Postman v2.1 collection with a request for each
+// of methods to w. Requests are grouped in a folder per receiver type.
+func writePostman(w io.Writer, name string, methods []Method) error {
+	collection := postmanCollection{
+		Info: postmanInfo{
+			Name:   name,
+			Schema: postmanSchema,
+		},
+		Item: []postmanFolder{},
+		Variable: []postmanVariable{
+			{Key: "baseUrl", Value: "http://localhost:8080"},
+		},
+	}
+
+	auth := false
+	for _, method := range methods {
+		i := slices.IndexFunc(collection.Item, func(folder postmanFolder) bool {
+			return folder.Name == method.ReceiverType
+		})
+		if i < 0 {
+			collection.Item = append(collection.Item, postmanFolder{Name: method.ReceiverType})
+			i = len(collection.Item) - 1
+		}
+		collection.Item[i].Item = append(collection.Item[i].Item, postmanItem{
+			Name:    method.Name,
+			Request: postmanRequestFor(method),
+		})
+		auth = auth || method.ApiMethod.Auth
+	}
+	if auth {
+		collection.Variable = append(collection.Variable, postmanVariable{Key: "authKey"})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(collection)
+}
+
+// postmanRequestFor returns the request calling method with the first of
+// its HTTP methods. Like in the OpenAPI spec, GET and DELETE parameters
+// are sent in the query and the parameters of other methods in a
+// form-encoded body.
+func postmanRequestFor(method Method) postmanRequest {
+	httpMethod := strings.TrimSpace(strings.Split(method.ApiMethod.Method, ",")[0])
+	req := postmanRequest{
+		Method: httpMethod,
+		Header: []postmanParam{},
+		URL:    postmanURL{Host: []string{"{{baseUrl}}"}},
+	}
+
+	if method.ApiMethod.Auth {
+		value := "{{authKey}}"
+		if method.ApiMethod.AuthScheme == AuthSchemeBearer {
+			value = "Bearer {{authKey}}"
+		}
+		req.Header = append(req.Header, postmanParam{Key: method.ApiMethod.AuthHeader, Value: value, Type: "text"})
+	}
+
+	for _, segment := range method.ApiMethod.PathSegments()[1:] {
+		if segment.Param {
+			req.URL.Path = append(req.URL.Path, ":"+segment.Value)
+			req.URL.Variable = append(req.URL.Variable, postmanParam{Key: segment.Value})
+		} else {
+			req.URL.Path = append(req.URL.Path, segment.Value)
+		}
+	}
+
+	var params []postmanParam
+	for _, field := range method.StructFields {
+		if slices.Contains(method.ApiMethod.PathParams(), field.ParamName()) {
+			continue
+		}
+		params = append(params, postmanParam{
+			Key:      field.ParamName(),
+			Value:    field.Tag.Default,
+			Disabled: !field.Tag.Required,
+		})
+	}
+
+	req.URL.Raw = "{{baseUrl}}/" + strings.Join(req.URL.Path, "/")
+	if httpMethod == "GET" || httpMethod == "DELETE" {
+		req.URL.Query = params
+		var query []string
+		for _, param := range params {
+			if !param.Disabled {
+				query = append(query, url.QueryEscape(param.Key)+"="+url.QueryEscape(param.Value))
+			}
+		}
+		if len(query) > 0 {
+			req.URL.Raw += "?" + strings.Join(query, "&")
+		}
+		return req
+	}
+
+	body := &postmanBody{Mode: "urlencoded", URLEncoded: []postmanParam{}}
+	for _, param := range params {
+		param.Type = "text"
+		body.URLEncoded = append(body.URLEncoded, param)
+	}
+	req.Body = body
+	return req
+}
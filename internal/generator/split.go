@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// generatedFile is the code of one output file.
+type generatedFile struct {
+	path string
+	code []byte
+}
+
+// SplitPath returns the path of the file the handlers of receiverType are
+// written to in outputDir by GenerateSplit, e.g. myapi_gen.go for MyApi.
+func SplitPath(outputDir, receiverType string) string {
+	return filepath.Join(outputDir, strings.ToLower(receiverType)+"_gen.go")
+}
+
+// SplitMocksPath returns the path of the file the mocks of inputFile are
+// written to in outputDir with -split, e.g. api_mock_gen.go for api.go.
+func SplitMocksPath(outputDir, inputFile string) string {
+	name := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+	return filepath.Join(outputDir, name+"_mock_gen.go")
+}
+
+// GenerateSplit is like GeneratePackage but writes the handlers of every
+// receiver type to its own file in outputDir, named by SplitPath. Each file
+// only imports what its handlers use. The declarations shared by all
+// receivers, such as ErrorStatuses, are written to the file of the first
+// receiver type in sorted order. The other outputs of opts are written as
+// by GeneratePackage.
+func GenerateSplit(inputFiles []string, outputDir string, opts Options) error {
+	pkg, files, err := generateSplitCode(inputFiles, outputDir, opts)
+	if err != nil {
+		return err
+	}
+
+	err = writeExtras(pkg, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		err = writeFile(file.path, file.code)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckSplit is like GenerateSplit but compares the generated code with the
+// existing output files like CheckPackage instead of writing anything.
+func CheckSplit(inputFiles []string, outputDir string, opts Options) (string, error) {
+	_, files, err := generateSplitCode(inputFiles, outputDir, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var diffs strings.Builder
+	for _, file := range files {
+		diff, err := checkFile(file.path, file.code)
+		if err != nil {
+			return "", err
+		}
+		diffs.WriteString(diff)
+	}
+	return diffs.String(), nil
+}
+
+// generateSplitCode parses the input files and returns the parsed package
+// along with the handler code of each receiver type, in sorted order.
+func generateSplitCode(inputFiles []string, outputDir string, opts Options) (*parsedPackage, []generatedFile, error) {
+	if len(inputFiles) == 0 {
+		return nil, nil, fmt.Errorf("no input files")
+	}
+
+	pkg, err := parseFiles(inputFiles, opts.Lax)
+	if err != nil {
+		return nil, nil, err
+	}
+	warn(opts, pkg)
+
+	groupedMethods := make(map[string][]Method)
+	for _, method := range pkg.Methods {
+		groupedMethods[method.ReceiverType] = append(groupedMethods[method.ReceiverType], method)
+	}
+	receiverTypes := make([]string, 0, len(groupedMethods))
+	for receiverType := range groupedMethods {
+		receiverTypes = append(receiverTypes, receiverType)
+	}
+	sort.Strings(receiverTypes)
+
+	tmpl, err := loadHandlerTemplate(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []generatedFile
+	paths := make(map[string]string)
+	for i, receiverType := range receiverTypes {
+		path := SplitPath(outputDir, receiverType)
+		if other, ok := paths[path]; ok {
+			return nil, nil, fmt.Errorf("receivers %s and %s would both be written to %s", other, receiverType, path)
+		}
+		paths[path] = receiverType
+
+		code, err := renderShared(tmpl, pkg.Name, groupedMethods[receiverType], opts, i == 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, generatedFile{path: path, code: code})
+	}
+	return pkg, files, nil
+}
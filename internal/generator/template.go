@@ -1,163 +1,1264 @@
 package generator
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 )
 
 var funcMap = template.FuncMap{
-	"toLower": strings.ToLower,
-	"join":    strings.Join,
+	"lower":          strings.ToLower,
+	"camel":          toCamel,
+	"snake":          toSnake,
+	"pascal":         toPascal,
+	"jsonTag":        jsonTag,
+	"join":           strings.Join,
+	"deref":          deref,
+	"derefFloat":     derefFloat,
+	"errorJSON":      errorJSON,
+	"invalid":        invalid,
+	"invalidError":   invalidError,
+	"requiredIfCond": requiredIfCond,
+	"allow":          allow,
+	"corsHeaders":    corsHeaders,
+	"hasPathParams":  hasPathParams,
+	"hasUUID":        hasUUID,
+	"httpMethods":    httpMethods,
+	"ginPath":        ginPath,
+	"introspectJSON": introspectJSON,
+	"statusConst":    statusConst,
 }
 
-var handlerTemplate = template.Must(template.New("handler").Funcs(funcMap).Parse(`
-// Code generated by gonerator. DO NOT EDIT.
+// deref returns the value i points to.
+func deref(i *int) int {
+	return *i
+}
+
+// derefFloat returns the value f points to.
+func derefFloat(f *float64) float64 {
+	return *f
+}
+
+// errorJSON returns a Go string literal holding the JSON error body for
+// msg, under errorKey, and, if set, the machine-readable code.
+func errorJSON(errorKey, msg, code string) string {
+	body := jsonString(errorKey) + `: ` + jsonString(msg)
+	if code != "" {
+		body += `, "code": ` + jsonString(code)
+	}
+	return strconv.Quote(`{` + body + `}`)
+}
+
+// jsonString returns s encoded as a JSON string without HTML escaping.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSpace(buf.String())
+}
+
+// statusConsts are the names of the net/http constants of 2xx statuses.
+var statusConsts = map[int]string{
+	http.StatusOK:                   "http.StatusOK",
+	http.StatusCreated:              "http.StatusCreated",
+	http.StatusAccepted:             "http.StatusAccepted",
+	http.StatusNonAuthoritativeInfo: "http.StatusNonAuthoritativeInfo",
+	http.StatusNoContent:            "http.StatusNoContent",
+	http.StatusResetContent:         "http.StatusResetContent",
+	http.StatusPartialContent:       "http.StatusPartialContent",
+	http.StatusMultiStatus:          "http.StatusMultiStatus",
+	http.StatusAlreadyReported:      "http.StatusAlreadyReported",
+	http.StatusIMUsed:               "http.StatusIMUsed",
+}
+
+// statusConst returns the Go expression of status in the generated code.
+func statusConst(status int) string {
+	if name, ok := statusConsts[status]; ok {
+		return name
+	}
+	return strconv.Itoa(status)
+}
+
+// allow returns the value of the Allow header for the comma-separated methods.
+func allow(methods string) string {
+	return strings.Join(httpMethods(methods), ", ")
+}
+
+// httpMethods splits the comma-separated methods.
+func httpMethods(methods string) []string {
+	parts := strings.Split(methods, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// corsHeaders returns the value of the Access-Control-Allow-Headers header
+// for method: the request headers it reads.
+func corsHeaders(method ApiMethod) string {
+	if method.Auth {
+		return "Content-Type, " + method.AuthHeader
+	}
+	return "Content-Type"
+}
 
+// ginPath returns the gin route path of method, where URL
+// parameters are written as :id instead of {id}.
+func ginPath(method ApiMethod) string {
+	var parts []string
+	for _, segment := range method.PathSegments() {
+		if segment.Param {
+			parts = append(parts, ":"+segment.Value)
+		} else {
+			parts = append(parts, segment.Value)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// hasPathParams reports whether the URL of any of methods has parameters.
+func hasPathParams(methods []Method) bool {
+	return anyMethod(methods, func(m Method) bool { return len(m.ApiMethod.PathParams()) > 0 })
+}
+
+// hasUUID reports whether any of methods has a field with the uuid rule.
+func hasUUID(methods []Method) bool {
+	return anyMethod(methods, func(m Method) bool {
+		return slices.ContainsFunc(m.StructFields, func(f StructField) bool { return f.Tag.UUID })
+	})
+}
+
+// invalid returns the statements run when a parameter fails validation
+// with msg and code. If collect is set, the parameter is validated in a func
+// returning the message, so the errors of all parameters can be collected.
+// Otherwise the message is returned to the client right away.
+func invalid(collect bool, msg, code string) string {
+	if collect {
+		return "return " + strconv.Quote(msg)
+	}
+	return "writeError(w, r, http.StatusBadRequest, " + strconv.Quote(msg) + ", " + strconv.Quote(code) + ")\nreturn"
+}
+
+// invalidError is like invalid, but for a parameter rejected by a custom
+// validator, whose error err holds the message.
+func invalidError(collect bool, code string) string {
+	if collect {
+		return "return err.Error()"
+	}
+	return "writeError(w, r, http.StatusBadRequest, err.Error(), " + strconv.Quote(code) + ")\nreturn"
+}
+
+// requiredIfCond returns the condition under which the required_if rule of
+// field makes it required: the parsed value of the other parameter equals
+// the value of the rule. The value is parsed as the type of the other
+// parameter and written as a Go literal, and other types, such as
+// time.Time, are an error.
+func requiredIfCond(method Method, field StructField) (string, error) {
+	requiredIf := field.Tag.RequiredIf
+	other := method.Field(requiredIf.Param)
+	var literal string
+	switch {
+	case other.IsString():
+		literal = strconv.Quote(requiredIf.Value)
+	case other.IsBool():
+		value, err := strconv.ParseBool(requiredIf.Value)
+		switch strings.ToLower(requiredIf.Value) {
+		case "on":
+			value, err = true, nil
+		case "off":
+			value, err = false, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if value {
+			return "params." + other.Name, nil
+		}
+		return "!params." + other.Name, nil
+	case other.IsUnsigned():
+		value, err := strconv.ParseUint(requiredIf.Value, 10, other.IntBits())
+		if err != nil {
+			return "", err
+		}
+		literal = strconv.FormatUint(value, 10)
+	case other.IsInteger():
+		value, err := strconv.ParseInt(requiredIf.Value, 10, other.IntBits())
+		if err != nil {
+			return "", err
+		}
+		literal = strconv.FormatInt(value, 10)
+	case other.IsFloat():
+		value, err := strconv.ParseFloat(requiredIf.Value, other.FloatBits())
+		if err != nil {
+			return "", err
+		}
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return "", fmt.Errorf("%s must be a finite number, got %q", requiredIf.Param, requiredIf.Value)
+		}
+		literal = strconv.FormatFloat(value, 'g', -1, other.FloatBits())
+	default:
+		return "", fmt.Errorf("cannot compare with %s parameter %s", other.Type, requiredIf.Param)
+	}
+	return "params." + other.Name + " == " + literal, nil
+}
+
+// loadHandlerTemplate returns the template handlers are generated with:
+// the one in opts.TemplateFile, parsed with the helper functions of the
+// built-in template, or handlerTemplate if it is not set.
+func loadHandlerTemplate(opts Options) (*template.Template, error) {
+	if opts.TemplateFile == "" {
+		return handlerTemplate, nil
+	}
+	text, err := os.ReadFile(opts.TemplateFile)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(opts.TemplateFile)).Funcs(funcMap).Parse(string(text))
+}
+
+var handlerTemplate = template.Must(template.New("handler").Funcs(funcMap).Parse(`
 package {{.PackageName}}
 
 import (
-    "encoding/json"
-    "net/http"
-    "net/url"
-    "os"
-    "strconv"
-    "strings"
+    {{range .Imports}}
+    {{.Name}} "{{.Path}}"
+    {{end}}
+    {{if eq .Router "chi"}}
+    "github.com/go-chi/chi/v5"
+    {{end}}
+    {{if eq .Router "gin"}}
+    "github.com/gin-gonic/gin"
+    {{end}}
+    {{if .Metrics}}
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+    {{end}}
+    {{if .Otel}}
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/trace"
+    {{end}}
 )
 
+// URLs of the API methods.
+const (
+{{- range $receiverType, $methods := .Methods}}
+{{- range $methods}}
+    URL{{$receiverType}}{{.Name}} = "{{.ApiMethod.Url}}"
+{{- end}}
+{{- end}}
+)
+
+{{if .Shared}}
+// RouteInfo describes the route of an API method.
+type RouteInfo struct {
+    // Name is the name of the API method.
+    Name string
+    // Methods are the HTTP methods the route accepts.
+    Methods []string
+    // Auth reports whether requests must be authenticated.
+    Auth bool
+    // Params are the names of the parameters of the method.
+    Params []string
+}
+
+{{if .Metrics}}
+var (
+    apiRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "api_requests_total",
+        Help: "Number of API requests by path and status.",
+    }, []string{"path", "status"})
+    apiRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+        Name:    "api_request_duration_seconds",
+        Help:    "Duration of API requests by path.",
+        Buckets: prometheus.DefBuckets,
+    }, []string{"path"})
+)
+
+// MetricsHandler returns a handler serving the API metrics to Prometheus.
+func MetricsHandler() http.Handler {
+    return promhttp.Handler()
+}
+{{end}}
+
+{{if and .Shared .Otel}}
+// tracer starts the spans of the API methods. It is obtained from the global
+// TracerProvider, so exporters are configured with otel.SetTracerProvider.
+var tracer = otel.Tracer("{{.PackageName}}")
+{{end}}
+
+// ErrorStatus maps the errors matching Err, as reported by errors.Is, to
+// the status of the response.
+type ErrorStatus struct {
+    Err    error
+    Status int
+}
+
+// ErrorStatuses maps errors returned by API methods to the status of the
+// response. An error is answered with the status of the first entry it
+// matches, so an error wrapping several of them always gets the same
+// status. Append entries in an init function of the package.
+var ErrorStatuses = []ErrorStatus{
+    {Err: context.DeadlineExceeded, Status: http.StatusGatewayTimeout},
+}
+
+// errorStatus returns the response status for an error returned by an API
+// method: the status of an ApiError, the status of the first entry of
+// ErrorStatuses err matches, or 500 Internal Server Error.
+func errorStatus(err error) int {
+    var apiErr ApiError
+    if errors.As(err, &apiErr) {
+        return apiErr.HTTPStatus
+    }
+    for _, entry := range ErrorStatuses {
+        if errors.Is(err, entry.Err) {
+            return entry.Status
+        }
+    }
+    return http.StatusInternalServerError
+}
+
+{{if eq .Envelope "response"}}
+// responseEnvelope is the body of successful responses. Encoding a struct
+// rather than a map saves allocating the map on every request.
+type responseEnvelope struct {
+    Error    string      "json:\"{{.ErrorKey}}\""
+    Response interface{} "json:\"{{.ResponseKey}}\""
+}
+{{else if eq .Envelope "data"}}
+// responseEnvelope is the body of successful responses. Encoding a struct
+// rather than a map saves allocating the map on every request.
+type responseEnvelope struct {
+    Data interface{} "json:\"{{.ResponseKey}}\""
+}
+{{end}}
+
+// maxPooledBufferSize is the capacity above which encoding buffers are
+// dropped rather than returned to jsonBufferPool, so a single large
+// response does not keep its memory alive.
+const maxPooledBufferSize = 64 << 10
+
+// jsonBuffer is a buffer responses are encoded into along with an
+// encoder writing to it, so neither is allocated per request.
+type jsonBuffer struct {
+    bytes.Buffer
+    enc *json.Encoder
+}
+
+// jsonBufferPool holds the *jsonBuffer values of writeJSON.
+var jsonBufferPool = sync.Pool{
+    New: func() interface{} {
+        buf := &jsonBuffer{}
+        buf.enc = json.NewEncoder(&buf.Buffer)
+        return buf
+    },
+}
+
+// writeJSON writes v encoded as JSON with status. The response is encoded
+// into a pooled buffer first, so an encoding error is answered with 500
+// instead of a truncated body.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    buf := jsonBufferPool.Get().(*jsonBuffer)
+    buf.Reset()
+    defer func() {
+        if buf.Cap() <= maxPooledBufferSize {
+            jsonBufferPool.Put(buf)
+        }
+    }()
+
+    err := buf.enc.Encode(v)
+    if err != nil {
+        http.Error(w, "{\"{{$.ErrorKey}}\": \"cannot encode response\"}", http.StatusInternalServerError)
+        return
+    }
+    w.WriteHeader(status)
+    w.Write(buf.Bytes())
+}
+
+// writeError answers with an error body holding msg and, unless it is
+// empty, the error code{{if .XML}}: as XML if r prefers it and as JSON
+// otherwise{{end}}. Like http.Error, the JSON body is sent as text/plain.
+func writeError(w http.ResponseWriter, r *http.Request, status int, msg, code string) {
+    {{- if .XML}}
+    if acceptsXML(r) {
+        writeXML(w, status, xmlEnvelope{Error: msg, Code: code})
+        return
+    }
+    {{- end}}
+    body := "{\"{{.ErrorKey}}\": " + jsonQuote(msg)
+    if code != "" {
+        body += ", \"code\": " + jsonQuote(code)
+    }
+    http.Error(w, body+"}", status)
+}
+
+// jsonQuote returns s encoded as a JSON string without HTML escaping.
+func jsonQuote(s string) string {
+    var buf bytes.Buffer
+    enc := json.NewEncoder(&buf)
+    enc.SetEscapeHTML(false)
+    enc.Encode(s)
+    return strings.TrimSuffix(buf.String(), "\n")
+}
+
+{{if .XML}}
+// xmlEnvelope is the root element of XML responses.
+type xmlEnvelope struct {
+    XMLName  xml.Name    "xml:\"envelope\""
+    Error    string      "xml:\"{{.ErrorKey}},omitempty\""
+    Code     string      "xml:\"code,omitempty\""
+    Errors   []string    "xml:\"{{.ErrorsKey}},omitempty\""
+    {{- if eq .Envelope "response"}}
+    Response interface{} "xml:\"{{.ResponseKey}},omitempty\""
+    {{- else if eq .Envelope "data"}}
+    Data     interface{} "xml:\"{{.ResponseKey}},omitempty\""
+    {{- end}}
+}
+
+// acceptsXML reports whether the Accept header of r prefers XML to JSON:
+// the q-value of application/xml or text/xml is higher than the q-value of
+// the most specific media range matching application/json. JSON is kept
+// on a tie.
+func acceptsXML(r *http.Request) bool {
+    var xmlQ, jsonQ float64
+    jsonSpecificity := 0
+    for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+        mediaType, params, _ := strings.Cut(mediaRange, ";")
+        q := 1.0
+        for _, param := range strings.Split(params, ";") {
+            if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+                q, _ = strconv.ParseFloat(value, 64)
+            }
+        }
+        specificity := 0
+        switch strings.TrimSpace(mediaType) {
+        case "application/xml", "text/xml":
+            if q > xmlQ {
+                xmlQ = q
+            }
+        case "application/json":
+            specificity = 3
+        case "application/*":
+            specificity = 2
+        case "*/*":
+            specificity = 1
+        }
+        if specificity > jsonSpecificity {
+            jsonQ, jsonSpecificity = q, specificity
+        }
+    }
+    return xmlQ > jsonQ
+}
+
+// writeXML writes v as an XML response with status.
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/xml")
+    w.WriteHeader(status)
+    io.WriteString(w, xml.Header)
+    xml.NewEncoder(w).Encode(v)
+}
+{{end}}
+
+{{if .Gzip}}
+// GzipMinSize is the size in bytes of the smallest response body that is
+// compressed. Smaller responses are written uncompressed.
+var GzipMinSize = 1024
+
+// acceptsGzip reports whether the Accept-Encoding header of r accepts gzip.
+func acceptsGzip(r *http.Request) bool {
+    encodings := r.Header.Get("Accept-Encoding")
+    for encodings != "" {
+        var encoding string
+        encoding, encodings, _ = strings.Cut(encodings, ",")
+        name, params, _ := strings.Cut(encoding, ";")
+        if strings.TrimSpace(name) == "gzip" {
+            return strings.ReplaceAll(params, " ", "") != "q=0"
+        }
+    }
+    return false
+}
+
+// gzipWriter compresses the response written to the wrapped ResponseWriter
+// with gzip once its body reaches GzipMinSize bytes. Until then the body
+// and status are buffered. Close must be called to write the rest.
+type gzipWriter struct {
+    http.ResponseWriter
+    status int
+    buf    []byte
+    gz     *gzip.Writer
+}
+
+func (w *gzipWriter) WriteHeader(status int) {
+    if w.status == 0 {
+        w.status = status
+    }
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+    if w.gz != nil {
+        return w.gz.Write(p)
+    }
+    w.buf = append(w.buf, p...)
+    if len(w.buf) >= GzipMinSize {
+        err := w.compress()
+        if err != nil {
+            return 0, err
+        }
+    }
+    return len(p), nil
+}
+
+// compress starts compressing the response, beginning with the buffered body.
+func (w *gzipWriter) compress() error {
+    w.Header().Del("Content-Length")
+    w.Header().Set("Content-Encoding", "gzip")
+    w.writeHeader()
+    w.gz = gzip.NewWriter(w.ResponseWriter)
+    _, err := w.gz.Write(w.buf)
+    w.buf = nil
+    return err
+}
+
+// writeHeader writes the buffered status, if any, to the wrapped ResponseWriter.
+func (w *gzipWriter) writeHeader() {
+    if w.status != 0 {
+        w.ResponseWriter.WriteHeader(w.status)
+    }
+}
+
+// Flush compresses the buffered body and sends all data written so far to the client.
+func (w *gzipWriter) Flush() {
+    if w.gz == nil && w.compress() != nil {
+        return
+    }
+    if w.gz.Flush() != nil {
+        return
+    }
+    if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+        flusher.Flush()
+    }
+}
+
+// Close writes the rest of the response, uncompressed if it is smaller than GzipMinSize.
+func (w *gzipWriter) Close() error {
+    if w.gz != nil {
+        return w.gz.Close()
+    }
+    w.writeHeader()
+    _, err := w.ResponseWriter.Write(w.buf)
+    return err
+}
+{{end}}
+{{if or .RequestID .Slog}}
+// requestContextKey is the type of the keys of the request-scoped values
+// ServeHTTP stores in the context passed to API methods.
+type requestContextKey int
+
+const (
+    // requestIDKey holds the request ID as a string.
+    requestIDKey requestContextKey = iota
+    {{- if .Slog}}
+    // loggerKey holds the *slog.Logger of the request.
+    loggerKey
+    {{- end}}
+)
+
+// RequestIDFromContext returns the ID of the request ctx was derived from:
+// its X-Request-ID header, or a random UUID if it had none. It returns ""
+// if ctx does not belong to a request served by the generated handlers.
+func RequestIDFromContext(ctx context.Context) string {
+    id, _ := ctx.Value(requestIDKey).(string)
+    return id
+}
+
+// newRequestID returns a random version 4 UUID.
+func newRequestID() string {
+    var b [16]byte
+    rand.Read(b[:])
+    b[6] = b[6]&0x0f | 0x40
+    b[8] = b[8]&0x3f | 0x80
+    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+{{end}}
+{{if .Slog}}
+// LoggerFromContext returns the logger of the request ctx was derived from,
+// which is slog.Default() with a request_id attribute, or slog.Default() if
+// ctx does not belong to a request served by the generated handlers.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+    if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+        return logger
+    }
+    return slog.Default()
+}
+{{end}}
+{{end}}
+
 {{range $receiverType, $methods := .Methods}}
+// {{$receiverType}}Routes describes the routes of {{$receiverType}} by URL.
+var {{$receiverType}}Routes = map[string]RouteInfo{
+    {{- range $methods}}
+    URL{{$receiverType}}{{.Name}}: {
+        Name: "{{.Name}}",
+        Methods: []string{ {{- range $i, $m := httpMethods .ApiMethod.Method}}{{if $i}}, {{end}}"{{$m}}"{{end -}} },
+        Auth: {{.ApiMethod.Auth}},
+        Params: []string{ {{- range $i, $f := .StructFields}}{{if $i}}, {{end}}"{{jsonTag $f}}"{{end -}} },
+    },
+    {{- end}}
+}
+
 {{range $methods}}
+{{$method := .}}
+{{range .StructFields}}
+{{if .Tag.Regex}}
+var regex{{$receiverType}}{{$method.Name}}{{.Name}} = regexp.MustCompile({{printf "%q" .Tag.Regex}})
+{{end}}
+{{if .Tag.EnumCI}}
+var enum{{$receiverType}}{{$method.Name}}{{.Name}} = []string{ {{- range $i, $v := .Tag.Enum}}{{if $i}}, {{end}}{{printf "%q" $v}}{{end -}} }
+{{else if and .Tag.Enum .IsInteger}}
+var enum{{$receiverType}}{{$method.Name}}{{.Name}} = map[{{.Type}}]bool{ {{- range $i, $v := .Tag.Enum}}{{if $i}}, {{end}}{{$v}}: true{{end -}} }
+{{else if .Tag.Enum}}
+var enum{{$receiverType}}{{$method.Name}}{{.Name}} = map[string]bool{ {{- range $i, $v := .Tag.Enum}}{{if $i}}, {{end}}{{printf "%q" $v}}: true{{end -}} }
+{{end}}
+{{end}}
+
 func (h *{{$receiverType}}) handler{{.Name}}(w http.ResponseWriter, r *http.Request) {
+    {{- if $.Otel}}
+    ctx, span := tracer.Start(r.Context(), r.Method+" {{.ApiMethod.Url}}", trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+        attribute.String("http.request.method", r.Method),
+        attribute.String("http.route", "{{.ApiMethod.Url}}"),
+    ))
+    r = r.WithContext(ctx)
+    sw := &statusWriter{{$receiverType}}{ResponseWriter: w, status: http.StatusOK}
+    w = sw
+    defer func() {
+        if err := recover(); err != nil {
+            span.SetStatus(codes.Error, fmt.Sprint("panic: ", err))
+            span.End()
+            panic(err)
+        }
+        span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+        if sw.status >= http.StatusInternalServerError {
+            span.SetStatus(codes.Error, http.StatusText(sw.status))
+        }
+        span.End()
+    }()
+    {{end}}
     {{if .ApiMethod.Auth}}
     authKey := os.Getenv("{{.ApiMethod.AuthEnvKey}}")
     if authKey == "" {
-        http.Error(w, "{\"error\": \"Server configuration error: missing auth key\"}", http.StatusInternalServerError)
+        writeError(w, r, http.StatusInternalServerError, "Server configuration error: missing auth key", "")
         return
     }
-    if r.Header.Get("X-Auth") != authKey {
-        http.Error(w, "{\"error\": \"unauthorized\"}", http.StatusForbidden)
+    {{if eq .ApiMethod.AuthScheme "bearer"}}
+    authToken, ok := strings.CutPrefix(r.Header.Get("{{.ApiMethod.AuthHeader}}"), "Bearer ")
+    if !ok || subtle.ConstantTimeCompare([]byte(authToken), []byte(authKey)) != 1 {
+        writeError(w, r, http.StatusForbidden, "unauthorized", "")
         return
     }
+    {{else}}
+    if subtle.ConstantTimeCompare([]byte(r.Header.Get("{{.ApiMethod.AuthHeader}}")), []byte(authKey)) != 1 {
+        writeError(w, r, http.StatusForbidden, "unauthorized", "")
+        return
+    }
+    {{end}}
     {{end}}
 
-    allowedMethods := strings.Split("{{.ApiMethod.Method}}", ",")
-    methodAllowed := false
-    for _, m := range allowedMethods {
-        if r.Method == strings.TrimSpace(m) {
-            methodAllowed = true
-            break
-        }
-    }
-    if !methodAllowed {
-        http.Error(w, "{\"error\": \"bad method\"}", http.StatusNotAcceptable)
+    switch r.Method {
+    case {{range $i, $m := httpMethods .ApiMethod.Method}}{{if $i}}, {{end}}"{{$m}}"{{end}}:
+    default:
+        {{- if $.StrictMethods}}
+        w.Header().Set("Allow", "{{allow .ApiMethod.Method}}")
+        writeError(w, r, http.StatusMethodNotAllowed, "bad method", "")
+        {{- else}}
+        writeError(w, r, http.StatusNotAcceptable, "bad method", "")
+        {{- end}}
         return
     }
 
     var params {{.InputType}}
 
+    r.Body = http.MaxBytesReader(w, r.Body, {{.ApiMethod.MaxBodyBytes}})
+
     var queryParams url.Values
     if r.Method == "GET" {
         queryParams = r.URL.Query()
+    } else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+        var body map[string]interface{}
+        decoder := json.NewDecoder(r.Body)
+        decoder.UseNumber()
+        err := decoder.Decode(&body)
+        var maxBytesErr *http.MaxBytesError
+        if errors.As(err, &maxBytesErr) {
+            writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+            return
+        }
+        if err != nil {
+            writeError(w, r, http.StatusBadRequest, "invalid json body", "")
+            return
+        }
+        queryParams = url.Values{}
+        for key, value := range body {
+            switch v := value.(type) {
+            case string:
+                queryParams.Set(key, v)
+            case json.Number:
+                queryParams.Set(key, v.String())
+            case bool:
+                queryParams.Set(key, strconv.FormatBool(v))
+            }
+        }
+    } else if contentType := r.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+        writeError(w, r, http.StatusUnsupportedMediaType, "unsupported content type", "")
+        return
     } else {
-        err := r.ParseForm()
+        var err error
+        if strings.HasPrefix(contentType, "multipart/form-data") {
+            err = r.ParseMultipartForm({{.ApiMethod.MaxBodyBytes}})
+        } else {
+            err = r.ParseForm()
+        }
+        var maxBytesErr *http.MaxBytesError
+        if errors.As(err, &maxBytesErr) {
+            writeError(w, r, http.StatusRequestEntityTooLarge, "request body too large", "")
+            return
+        }
         if err != nil {
-            http.Error(w, "{\"error\": \"" + err.Error() + "\"}", http.StatusBadRequest)
+            writeError(w, r, http.StatusBadRequest, err.Error(), "")
             return
         }
         queryParams = r.Form
     }
+    {{- if .ApiMethod.PathParams}}
 
+    pathSegments := strings.Split(r.URL.Path, "/")
+    {{- range $i, $segment := .ApiMethod.PathSegments}}
+    {{- if $segment.Param}}
+    queryParams.Set("{{$segment.Value}}", pathSegments[{{$i}}])
+    {{- end}}
+    {{- end}}
+    {{- end}}
+
+    {{$collect := or $.CollectErrors .ApiMethod.CollectErrors}}
+    {{- if $collect}}
+    var validationErrors []string
+    {{- end}}
     {{range .StructFields}}
-    {{if eq .Type "int"}}
-    {{.Name}}Str := queryParams.Get("{{if .Tag.ParamName}}{{.Tag.ParamName}}{{else}}{{toLower .Name}}{{end}}")
+    {{- if .Tag.Aliases}}
+    {{$paramName := jsonTag .}}
+    if !queryParams.Has("{{$paramName}}") {
+        switch {
+        {{- range .Tag.Aliases}}
+        case queryParams.Has("{{.}}"):
+            queryParams["{{$paramName}}"] = queryParams["{{.}}"]
+        {{- end}}
+        }
+    }
+    {{- end}}
+    {{- if $collect}}
+    if msg := func() string {
+    {{- end}}
+    {{if .IsInteger}}
+    {{.Name}}Str := queryParams.Get("{{jsonTag .}}")
+    {{if .Tag.Default}}
+    if !queryParams.Has("{{jsonTag .}}") {
+        {{.Name}}Str = "{{.Tag.Default}}"
+    }
+    {{end}}
     if {{.Name}}Str != "" {
-        {{.Name}}Val, err := strconv.Atoi({{.Name}}Str)
+        {{- if .IsUnsigned}}
+        {{.Name}}Val, err := strconv.ParseUint({{.Name}}Str, 10, {{.IntBits}})
+        {{- else}}
+        {{.Name}}Val, err := strconv.ParseInt({{.Name}}Str, 10, {{.IntBits}})
+        {{- end}}
         if err != nil {
-            http.Error(w, "{\"error\": \"{{toLower .Name}} must be int\"}", http.StatusBadRequest)
-            return
+            {{invalid $collect (or .Tag.Message (printf "%s must be %s" .ParamName .Type)) ($method.ErrorCode .)}}
         }
         {{if .Tag.Min}}
-        if {{.Name}}Val < {{.Tag.Min}} {
-            http.Error(w, "{\"error\": \"{{toLower .Name}} must be >= {{.Tag.Min}}\"}", http.StatusBadRequest)
-            return
+        if {{.Name}}Val {{if .Tag.MinExclusive}}<={{else}}<{{end}} {{.Tag.Min}} {
+            {{invalid $collect (or .Tag.Message (printf "%s must be %s %d" .ParamName .Tag.MinOp (deref .Tag.Min))) ($method.ErrorCode .)}}
         }
         {{end}}
         {{if .Tag.Max}}
-        if {{.Name}}Val > {{.Tag.Max}} {
-            http.Error(w, "{\"error\": \"{{toLower .Name}} must be <= {{.Tag.Max}}\"}", http.StatusBadRequest)
-            return
+        if {{.Name}}Val {{if .Tag.MaxExclusive}}>={{else}}>{{end}} {{.Tag.Max}} {
+            {{invalid $collect (or .Tag.Message (printf "%s must be %s %d" .ParamName .Tag.MaxOp (deref .Tag.Max))) ($method.ErrorCode .)}}
         }
         {{end}}
-        params.{{.Name}} = {{.Name}}Val
+        {{if .Tag.MultipleOf}}
+        if {{.Name}}Val%{{.Tag.MultipleOf}} != 0 {
+            {{invalid $collect (or .Tag.Message (printf "%s must be a multiple of %d" .ParamName (deref .Tag.MultipleOf))) ($method.ErrorCode .)}}
+        }
+        {{end}}
+        {{if .Tag.Enum}}
+        if !enum{{$receiverType}}{{$method.Name}}{{.Name}}[{{.Type}}({{.Name}}Val)] {
+            {{invalid $collect (or .Tag.Message (printf "%s must be one of [%s]" .ParamName (join .Tag.Enum ", "))) ($method.ErrorCode .)}}
+        }
+        {{end}}
+        params.{{.Name}} = {{.Type}}({{.Name}}Val)
     }
-    {{else}}
-    params.{{.Name}} = queryParams.Get("{{if .Tag.ParamName}}{{.Tag.ParamName}}{{else}}{{toLower .Name}}{{end}}")
+    {{else if .IsFloat}}
+    {{.Name}}Str := queryParams.Get("{{jsonTag .}}")
+    {{if .Tag.Default}}
+    if !queryParams.Has("{{jsonTag .}}") {
+        {{.Name}}Str = "{{.Tag.Default}}"
+    }
+    {{end}}
+    if {{.Name}}Str != "" {
+        {{.Name}}Val, err := strconv.ParseFloat({{.Name}}Str, {{.FloatBits}})
+        if err != nil {
+            {{invalid $collect (or .Tag.Message (printf "%s must be float" .ParamName)) ($method.ErrorCode .)}}
+        }
+        {{if .Tag.MinFloat}}
+        if {{.Name}}Val {{if .Tag.MinExclusive}}<={{else}}<{{end}} {{.Tag.MinFloat}} {
+            {{invalid $collect (or .Tag.Message (printf "%s must be %s %v" .ParamName .Tag.MinOp (derefFloat .Tag.MinFloat))) ($method.ErrorCode .)}}
+        }
+        {{end}}
+        {{if .Tag.MaxFloat}}
+        if {{.Name}}Val {{if .Tag.MaxExclusive}}>={{else}}>{{end}} {{.Tag.MaxFloat}} {
+            {{invalid $collect (or .Tag.Message (printf "%s must be %s %v" .ParamName .Tag.MaxOp (derefFloat .Tag.MaxFloat))) ($method.ErrorCode .)}}
+        }
+        {{end}}
+        params.{{.Name}} = {{.Type}}({{.Name}}Val)
+    }
+    {{else if .IsBool}}
+    {{.Name}}Str := queryParams.Get("{{jsonTag .}}")
+    {{if .Tag.Required}}
+    if {{.Name}}Str == "" {
+        {{invalid $collect (or .Tag.Message (printf "%s must be not empty" .ParamName)) ($method.ErrorCode .)}}
+    }
+    {{end}}
+    {{if .Tag.Default}}
+    if !queryParams.Has("{{jsonTag .}}") {
+        {{.Name}}Str = "{{.Tag.Default}}"
+    }
+    {{end}}
+    if {{.Name}}Str != "" {
+        switch strings.ToLower({{.Name}}Str) {
+        case "on":
+            params.{{.Name}} = true
+        case "off":
+            params.{{.Name}} = false
+        default:
+            {{.Name}}Val, err := strconv.ParseBool({{.Name}}Str)
+            if err != nil {
+                {{invalid $collect (or .Tag.Message (printf "%s must be bool" .ParamName)) ($method.ErrorCode .)}}
+            }
+            params.{{.Name}} = {{.Name}}Val
+        }
+    }
+    {{else if .IsString}}
+    params.{{.Name}} = {{if .Tag.Trim}}strings.TrimSpace({{end}}queryParams.Get("{{jsonTag .}}"){{if .Tag.Trim}}){{end}}
     {{if .Tag.Required}}
     if params.{{.Name}} == "" {
-        http.Error(w, "{\"error\": \"{{toLower .Name}} must be not empty\"}", http.StatusBadRequest)
-        return
+        {{invalid $collect (or .Tag.Message (printf "%s must be not empty" .ParamName)) ($method.ErrorCode .)}}
+    }
+    {{end}}
+    {{if .Tag.Email}}
+    if params.{{.Name}} != "" {
+        if _, err := mail.ParseAddress(params.{{.Name}}); err != nil {
+            {{invalid $collect (or .Tag.Message (printf "%s must be a valid email" .ParamName)) ($method.ErrorCode .)}}
+        }
+    }
+    {{end}}
+    {{if .Tag.UUID}}
+    if params.{{.Name}} != "" && !isUUID{{$receiverType}}(params.{{.Name}}) {
+        {{invalid $collect (or .Tag.Message (printf "%s must be a valid UUID" .ParamName)) ($method.ErrorCode .)}}
+    }
+    {{end}}
+    {{if .Tag.DateLayout}}
+    if params.{{.Name}} != "" {
+        if _, err := time.Parse({{printf "%q" .Tag.DateLayout}}, params.{{.Name}}); err != nil {
+            {{invalid $collect (or .Tag.Message (printf "%s must be a date in the layout %s" .ParamName .Tag.DateLayout)) ($method.ErrorCode .)}}
+        }
     }
     {{end}}
     {{if .Tag.Min}}
-    if len(params.{{.Name}}) < {{.Tag.Min}} {
-        http.Error(w, "{\"error\": \"{{toLower .Name}} len must be >= {{.Tag.Min}}\"}", http.StatusBadRequest)
-        return
+    if len(params.{{.Name}}) {{if .Tag.MinExclusive}}<={{else}}<{{end}} {{.Tag.Min}} {
+        {{invalid $collect (or .Tag.Message (printf "%s len must be %s %d" .ParamName .Tag.MinOp (deref .Tag.Min))) ($method.ErrorCode .)}}
     }
     {{end}}
     {{if .Tag.Max}}
-    if len(params.{{.Name}}) > {{.Tag.Max}} {
-        http.Error(w, "{\"error\": \"{{toLower .Name}} len must be <= {{.Tag.Max}}\"}", http.StatusBadRequest)
-        return
+    if len(params.{{.Name}}) {{if .Tag.MaxExclusive}}>={{else}}>{{end}} {{.Tag.Max}} {
+        {{invalid $collect (or .Tag.Message (printf "%s len must be %s %d" .ParamName .Tag.MaxOp (deref .Tag.Max))) ($method.ErrorCode .)}}
+    }
+    {{end}}
+    {{if .Tag.Regex}}
+    if params.{{.Name}} != "" && !regex{{$receiverType}}{{$method.Name}}{{.Name}}.MatchString(params.{{.Name}}) {
+        {{invalid $collect (or .Tag.Message (printf "%s must match pattern %s" .ParamName .Tag.Regex)) ($method.ErrorCode .)}}
     }
     {{end}}
-    {{if .Tag.Enum}}
-    validValues := []string{ {{range .Tag.Enum}}"{{.}}", {{end}} }
-    isValid := false
-    for _, v := range validValues {
-        if params.{{.Name}} == v {
-            isValid = true
+    {{if .Tag.EnumCI}}
+    {{.Name}}Valid := false
+    for _, v := range enum{{$receiverType}}{{$method.Name}}{{.Name}} {
+        if strings.EqualFold(params.{{.Name}}, v) {
+            params.{{.Name}} = v
+            {{.Name}}Valid = true
             break
         }
     }
-    if !isValid && params.{{.Name}} != "" {
-        http.Error(w, "{\"error\": \"{{toLower .Name}} must be one of [" + strings.Join(validValues, ", ") + "]\"}", http.StatusBadRequest)
-        return
+    if !{{.Name}}Valid && params.{{.Name}} != "" {
+        {{invalid $collect (or .Tag.Message (printf "%s must be one of [%s]" .ParamName (join .Tag.Enum ", "))) ($method.ErrorCode .)}}
+    }
+    {{else if .Tag.Enum}}
+    if !enum{{$receiverType}}{{$method.Name}}{{.Name}}[params.{{.Name}}] && params.{{.Name}} != "" {
+        {{invalid $collect (or .Tag.Message (printf "%s must be one of [%s]" .ParamName (join .Tag.Enum ", "))) ($method.ErrorCode .)}}
     }
     {{end}}
     {{if .Tag.Default}}
-    if params.{{.Name}} == "" {
+    if !queryParams.Has("{{jsonTag .}}") {
         params.{{.Name}} = "{{.Tag.Default}}"
     }
     {{end}}
+    {{else if .IsTime}}
+    {{.Name}}Str := queryParams.Get("{{jsonTag .}}")
+    {{if .Tag.Required}}
+    if {{.Name}}Str == "" {
+        {{invalid $collect (or .Tag.Message (printf "%s must be not empty" .ParamName)) ($method.ErrorCode .)}}
+    }
+    {{end}}
+    {{if .Tag.Default}}
+    if !queryParams.Has("{{jsonTag .}}") {
+        {{.Name}}Str = "{{.Tag.Default}}"
+    }
+    {{end}}
+    if {{.Name}}Str != "" {
+        {{.Name}}Val, err := time.Parse({{printf "%q" .TimeLayout}}, {{.Name}}Str)
+        if err != nil {
+            {{invalid $collect (or .Tag.Message (printf "%s must be a date in the layout %s" .ParamName .TimeLayout)) ($method.ErrorCode .)}}
+        }
+        params.{{.Name}} = {{.Name}}Val
+    }
     {{end}}
+    {{if .Tag.Custom}}
+    if err := h.{{.Tag.Custom}}(params.{{.Name}}); err != nil {
+        {{- if .Tag.Message}}
+        {{invalid $collect .Tag.Message ($method.ErrorCode .)}}
+        {{- else}}
+        {{invalidError $collect ($method.ErrorCode .)}}
+        {{- end}}
+    }
+    {{end}}
+    {{- if $collect}}
+        return ""
+    }(); msg != "" {
+        validationErrors = append(validationErrors, msg)
+    }
+    {{- end}}
     {{end}}
+    {{- range .StructFields}}
+    {{- if .Tag.RequiredIf}}
+    {{- $msg := or .Tag.Message (printf "%s must be not empty when %s is %s" .ParamName .Tag.RequiredIf.Param .Tag.RequiredIf.Value)}}
+    if {{requiredIfCond $method .}} && queryParams.Get("{{jsonTag .}}") == "" {
+        {{- if $collect}}
+        validationErrors = append(validationErrors, {{printf "%q" $msg}})
+        {{- else}}
+        {{invalid false $msg ($method.ErrorCode .)}}
+        {{- end}}
+    }
+    {{- end}}
+    {{- end}}
+    {{- if $collect}}
+    if len(validationErrors) > 0 {
+        {{- if $.XML}}
+        if acceptsXML(r) {
+            writeXML(w, http.StatusBadRequest, xmlEnvelope{Errors: validationErrors})
+            return
+        }
+        {{- end}}
+        body, _ := json.Marshal(map[string][]string{ {{- printf "%q" $.ErrorsKey}}: validationErrors})
+        http.Error(w, string(body), http.StatusBadRequest)
+        return
+    }
+    {{- end}}
 
-    res, err := h.{{.Name}}(r.Context(), params)
+    {{if .ApiMethod.TimeoutMs}}
+    ctx, cancel := context.WithTimeout(r.Context(), {{.ApiMethod.TimeoutMs}}*time.Millisecond)
+    defer cancel()
+    {{if eq .ApiMethod.SuccessStatus 204}}_{{else}}res{{end}}, err := h.{{.Name}}({{if .WithContext}}ctx, {{end}}{{if .InputPointer}}&{{end}}params{{if .WithRequest}}, r{{end}})
+    {{else}}
+    {{if eq .ApiMethod.SuccessStatus 204}}_{{else}}res{{end}}, err := h.{{.Name}}({{if .WithContext}}r.Context(), {{end}}{{if .InputPointer}}&{{end}}params{{if .WithRequest}}, r{{end}})
+    {{end}}
     if err != nil {
-        if apiErr, ok := err.(ApiError); ok {
-            http.Error(w, "{\"error\": \"" + apiErr.Error() + "\"}", apiErr.HTTPStatus)
-        } else {
-            http.Error(w, "{\"error\": \"" + err.Error() + "\"}", http.StatusInternalServerError)
+        {{- if $.Otel}}
+        span.RecordError(err)
+        {{- end}}
+        status := errorStatus(err)
+        {{- if $.HideInternalErrors}}
+        if status >= http.StatusInternalServerError {
+            log.Printf("error serving %s: %v", r.URL.Path, err)
+            writeError(w, r, status, "internal server error", "")
+            return
         }
+        {{- end}}
+        writeError(w, r, status, err.Error(), "")
         return
     }
 
-    w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(map[string]interface{}{
-        "error":    "",
-        "response": res,
-    })
+    {{- $status := statusConst .ApiMethod.SuccessStatus}}
+    {{- if eq .ApiMethod.SuccessStatus 204}}
+    w.WriteHeader({{$status}})
+    {{- else}}
+
+    {{- if $.XML}}
+    if acceptsXML(r) {
+        {{- if eq $.Envelope "data"}}
+        writeXML(w, {{$status}}, xmlEnvelope{Data: res})
+        {{- else if eq $.Envelope "bare"}}
+        writeXML(w, {{$status}}, res)
+        {{- else}}
+        writeXML(w, {{$status}}, xmlEnvelope{Response: res})
+        {{- end}}
+        return
+    }
+    {{- end}}
+
+    {{- if eq $.Envelope "bare"}}
+    writeJSON(w, {{$status}}, res)
+    {{- else if eq $.Envelope "data"}}
+    writeJSON(w, {{$status}}, responseEnvelope{Data: res})
+    {{- else}}
+    writeJSON(w, {{$status}}, responseEnvelope{Response: res})
+    {{- end}}
+    {{- end}}
+}
+{{end}}
+
+{{if $.IntrospectURL}}
+// introspection{{$receiverType}} describes the API methods of {{$receiverType}}.
+const introspection{{$receiverType}} = {{introspectJSON $methods}}
+{{end}}
+
+{{if hasUUID $methods}}
+// isUUID{{$receiverType}} reports whether s is a UUID in the canonical
+// 8-4-4-4-12 hexadecimal form.
+func isUUID{{$receiverType}}(s string) bool {
+    if len(s) != 36 {
+        return false
+    }
+    for i := 0; i < len(s); i++ {
+        switch c := s[i]; i {
+        case 8, 13, 18, 23:
+            if c != '-' {
+                return false
+            }
+        default:
+            if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+                return false
+            }
+        }
+    }
+    return true
+}
+{{end}}
+
+{{if or $.Logging $.Metrics $.Otel}}
+// statusWriter{{$receiverType}} records the status code written to the wrapped ResponseWriter.
+type statusWriter{{$receiverType}} struct {
+    http.ResponseWriter
+    status int
+}
+
+func (w *statusWriter{{$receiverType}}) WriteHeader(status int) {
+    w.status = status
+    w.ResponseWriter.WriteHeader(status)
+}
+{{end}}
+
+// Register{{$receiverType}}Routes registers srv on mux for the URL of every API method
+// of {{$receiverType}}, so it can be served alongside other routes.
+{{- if or $.IntrospectURL $.HealthzURL}}
+// Routes shared by all receivers are only registered if mux does not serve them yet.
+{{- end}}
+func Register{{$receiverType}}Routes(mux *http.ServeMux, srv *{{$receiverType}}) {
+    {{- range $methods}}
+    mux.Handle("{{.ApiMethod.Url}}", srv)
+    {{- end}}
+    {{- if $.IntrospectURL}}
+    if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "{{$.IntrospectURL}}"}}); pattern == "" {
+        mux.Handle("{{$.IntrospectURL}}", srv)
+    }
+    {{- end}}
+    {{- if $.HealthzURL}}
+    if _, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: "{{$.HealthzURL}}"}}); pattern == "" {
+        mux.Handle("{{$.HealthzURL}}", srv)
+    }
+    {{- end}}
+}
+
+{{if eq $.Router "chi"}}
+// Register{{$receiverType}}Chi registers srv on r for the HTTP methods and URL of every
+// API method of {{$receiverType}}. URL parameters like {id} are chi URL parameters.
+func Register{{$receiverType}}Chi(r chi.Router, srv *{{$receiverType}}) {
+    {{- range $methods}}
+    {{- $url := .ApiMethod.Url}}
+    {{- range httpMethods .ApiMethod.Method}}
+    r.Method("{{.}}", "{{$url}}", srv)
+    {{- end}}
+    {{- end}}
+    {{- if $.IntrospectURL}}
+    if !r.Match(chi.NewRouteContext(), "GET", "{{$.IntrospectURL}}") {
+        r.Method("GET", "{{$.IntrospectURL}}", srv)
+    }
+    {{- end}}
+    {{- if $.HealthzURL}}
+    if !r.Match(chi.NewRouteContext(), "GET", "{{$.HealthzURL}}") {
+        r.Method("GET", "{{$.HealthzURL}}", srv)
+    }
+    {{- end}}
+}
+{{end}}
+
+{{if eq $.Router "gin"}}
+// Register{{$receiverType}}Gin registers srv on r for the HTTP methods and URL of every
+// API method of {{$receiverType}}. URL parameters like {id} are gin parameters like :id.
+func Register{{$receiverType}}Gin(r gin.IRouter, srv *{{$receiverType}}) {
+    handler := gin.WrapH(srv)
+    {{- range $methods}}
+    {{- $path := ginPath .ApiMethod}}
+    {{- range httpMethods .ApiMethod.Method}}
+    r.Handle("{{.}}", "{{$path}}", handler)
+    {{- end}}
+    {{- end}}
+    {{- if $.IntrospectURL}}
+    r.Handle("GET", "{{$.IntrospectURL}}", handler)
+    {{- end}}
+    {{- if $.HealthzURL}}
+    r.Handle("GET", "{{$.HealthzURL}}", handler)
+    {{- end}}
 }
 {{end}}
 
 func (h *{{$receiverType}}) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    {{- if or $.RequestID $.Slog}}
+    requestID := r.Header.Get("X-Request-ID")
+    if requestID == "" {
+        requestID = newRequestID()
+    }
+    w.Header().Set("X-Request-ID", requestID)
+    ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+    {{- if $.Slog}}
+    ctx = context.WithValue(ctx, loggerKey, slog.Default().With("request_id", requestID))
+    {{- end}}
+    r = r.WithContext(ctx)
+    {{end}}
+    {{- if or $.Logging $.Metrics}}
+    start := time.Now()
+    sw := &statusWriter{{$receiverType}}{ResponseWriter: w, status: http.StatusOK}
+    w = sw
+    defer func() {
+        {{- if and $.Logging $.Slog}}
+        LoggerFromContext(r.Context()).Info("request", "method", r.Method, "path", r.URL.Path, "status", sw.status, "duration", time.Since(start))
+        {{- else if $.Logging}}
+        log.Printf("%s %s %d %s", r.Method, r.URL.Path, sw.status, time.Since(start))
+        {{- end}}
+        {{- if $.Metrics}}
+        apiRequestsTotal.WithLabelValues(r.URL.Path, strconv.Itoa(sw.status)).Inc()
+        apiRequestDuration.WithLabelValues(r.URL.Path).Observe(time.Since(start).Seconds())
+        {{- end}}
+    }()
+    {{end}}
+    {{- if $.Gzip}}
+    w.Header().Add("Vary", "Accept-Encoding")
+    if acceptsGzip(r) {
+        gw := &gzipWriter{ResponseWriter: w}
+        defer gw.Close()
+        w = gw
+    }
+    {{end}}
+    {{- if not $.NoRecover}}
+    defer func() {
+        if err := recover(); err != nil {
+            if err == http.ErrAbortHandler {
+                panic(err)
+            }
+            {{- if $.Slog}}
+            LoggerFromContext(r.Context()).Error("panic serving request", "path", r.URL.Path, "panic", err)
+            {{- else}}
+            log.Printf("panic serving %s: %v", r.URL.Path, err)
+            {{- end}}
+            writeError(w, r, http.StatusInternalServerError, "internal server error", "")
+        }
+    }()
+    {{end}}
+    {{- if $.CORSOrigin}}
+    w.Header().Set("Access-Control-Allow-Origin", {{printf "%q" $.CORSOrigin}})
+    if r.Method == http.MethodOptions {
+        {{- if hasPathParams $methods}}
+        segments := strings.Split(r.URL.Path, "/")
+        {{- end}}
+        switch {
+        {{- range $methods}}
+        {{- if not .ApiMethod.PathParams}}
+        case r.URL.Path == "{{.ApiMethod.Url}}":
+            w.Header().Set("Access-Control-Allow-Methods", "{{if $.CORSMethods}}{{join $.CORSMethods ", "}}{{else}}{{allow .ApiMethod.Method}}{{end}}")
+            w.Header().Set("Access-Control-Allow-Headers", "{{corsHeaders .ApiMethod}}{{range $.CORSHeaders}}, {{.}}{{end}}")
+            w.WriteHeader(http.StatusNoContent)
+            return
+        {{- end}}
+        {{- end}}
+        {{- range $methods}}
+        {{- if .ApiMethod.PathParams}}
+        case {{template "pathMatch" .ApiMethod}}:
+            w.Header().Set("Access-Control-Allow-Methods", "{{if $.CORSMethods}}{{join $.CORSMethods ", "}}{{else}}{{allow .ApiMethod.Method}}{{end}}")
+            w.Header().Set("Access-Control-Allow-Headers", "{{corsHeaders .ApiMethod}}{{range $.CORSHeaders}}, {{.}}{{end}}")
+            w.WriteHeader(http.StatusNoContent)
+            return
+        {{- end}}
+        {{- end}}
+        }
+    }
+    {{end}}
     switch r.URL.Path {
+    {{- if $.IntrospectURL}}
+    case "{{$.IntrospectURL}}":
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(introspection{{$receiverType}}))
+    {{- end}}
+    {{- if $.HealthzURL}}
+    case "{{$.HealthzURL}}":
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte("{\"status\":\"ok\"}"))
+    {{- end}}
     {{range $methods}}
+    {{- if not .ApiMethod.PathParams}}
     case "{{.ApiMethod.Url}}":
         h.handler{{.Name}}(w, r)
+    {{- end}}
     {{end}}
     default:
-        http.Error(w, "{\"error\": \"unknown method\"}", http.StatusNotFound)
+        {{- if hasPathParams $methods}}
+        // URLs with parameters are matched segment by segment
+        segments := strings.Split(r.URL.Path, "/")
+        {{- range $methods}}
+        {{- if .ApiMethod.PathParams}}
+        if {{template "pathMatch" .ApiMethod}} {
+            h.handler{{.Name}}(w, r)
+            return
+        }
+        {{- end}}
+        {{- end}}
+        {{- end}}
+        writeError(w, r, http.StatusNotFound, "unknown method", "")
     }
 }
 {{end}}
+
+{{define "pathMatch" -}}
+len(segments) == {{len .PathSegments}}
+{{- range $i, $segment := .PathSegments}}
+{{- if $segment.Param}} && segments[{{$i}}] != ""
+{{- else}} && segments[{{$i}}] == "{{$segment.Value}}"
+{{- end}}
+{{- end}}
+{{- end}}
 `))
@@ -0,0 +1,212 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// FileCache remembers the input files generated from and their modification
+// times, so watch mode only regenerates what changed since the last run.
+// It is not safe for concurrent use.
+type FileCache struct {
+	// modTimes are the modification times of the files passed to Changed
+	modTimes map[string]time.Time
+
+	// dirs are the parsed source directories of GenerateFiles
+	dirs map[string]*cachedDir
+}
+
+// cachedDir is a parsed source directory.
+type cachedDir struct {
+	// files are the modification times of the source files of the
+	// directory when it was parsed
+	files map[string]time.Time
+
+	// methods are the API methods of every input file an output file
+	// was written for, keyed by the cleaned input file path
+	methods map[string][]Method
+}
+
+// NewFileCache returns an empty FileCache.
+func NewFileCache() *FileCache {
+	return &FileCache{
+		modTimes: make(map[string]time.Time),
+		dirs:     make(map[string]*cachedDir),
+	}
+}
+
+// Changed reports whether any of files was modified, created or removed
+// since the last call, or whether this is the first call for one of them.
+func (c *FileCache) Changed(files []string) (bool, error) {
+	changed := false
+	for _, file := range files {
+		modTime, err := modTime(file)
+		if err != nil {
+			return false, err
+		}
+		if last, ok := c.modTimes[file]; !ok || !last.Equal(modTime) {
+			changed = true
+		}
+		c.modTimes[file] = modTime
+	}
+	return changed, nil
+}
+
+// GenerateDir is like GenerateDirWithOptions but skips the work that c
+// shows to be unnecessary, like GenerateFiles.
+func (c *FileCache) GenerateDir(dir, outPattern string, opts Options) ([]string, error) {
+	inputFiles, opts, err := dirInputs(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenerateFiles(inputFiles, outPattern, opts)
+}
+
+// GenerateFiles is like the package-level GenerateFiles but only parses
+// the directories whose source files changed since the last call, and only
+// renders the output files of input files whose API methods changed. It
+// returns the paths of the output files written, leaving out those that
+// already held the generated code.
+//
+// A directory that fails to parse is not parsed again until one of its
+// files changes, so the error is only returned once per change.
+func (c *FileCache) GenerateFiles(inputFiles []string, outPattern string, opts Options) ([]string, error) {
+	if outPattern == "" {
+		outPattern = DefaultOutPattern
+	}
+	err := checkOutputPaths(inputFiles, outPattern)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := loadHandlerTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	var firstErr error
+	parsed := make(map[string]map[string]*parsedPackage)
+	for _, inputFile := range inputFiles {
+		dir := filepath.Dir(inputFile)
+		packages, ok := parsed[dir]
+		if !ok {
+			var err error
+			packages, err = c.parseDir(dir, opts)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			warnDir(opts, packages)
+			parsed[dir] = packages
+		}
+		if packages == nil {
+			continue
+		}
+
+		var packageName string
+		var methods []Method
+		for _, pkg := range packages {
+			for _, method := range pkg.Methods {
+				if filepath.Clean(method.File) == filepath.Clean(inputFile) {
+					packageName = pkg.Name
+					methods = append(methods, method)
+				}
+			}
+		}
+		cached := c.dirs[dir]
+		if last, ok := cached.methods[filepath.Clean(inputFile)]; ok && reflect.DeepEqual(last, methods) {
+			continue
+		}
+		if len(methods) == 0 {
+			cached.methods[filepath.Clean(inputFile)] = nil
+			continue
+		}
+
+		outputFile := OutputPath(inputFile, outPattern)
+		changed := false
+		code, err := render(tmpl, packageName, methods, opts)
+		if err == nil {
+			changed, err = writeFileChanged(outputFile, code)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		cached.methods[filepath.Clean(inputFile)] = methods
+		if changed {
+			written = append(written, outputFile)
+		}
+	}
+	return written, firstErr
+}
+
+// parseDir parses dir like the package-level parseDir if any of its source
+// files changed since it was last parsed. It returns nil packages if dir is
+// unchanged, so its outputs are up to date.
+func (c *FileCache) parseDir(dir string, opts Options) (map[string]*parsedPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]time.Time)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !isSourceFile(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files[path] = info.ModTime()
+	}
+
+	cached, ok := c.dirs[dir]
+	if ok && sameModTimes(cached.files, files) {
+		return nil, nil
+	}
+	if !ok {
+		cached = &cachedDir{methods: make(map[string][]Method)}
+		c.dirs[dir] = cached
+	}
+	cached.files = files
+
+	packages, err := parseDir(dir, opts)
+	if err != nil {
+		// Generate every output again once the error is fixed
+		cached.methods = make(map[string][]Method)
+		return nil, err
+	}
+	return packages, nil
+}
+
+// sameModTimes reports whether a and b hold the same files with the same
+// modification times.
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, modTime := range a {
+		if other, ok := b[file]; !ok || !other.Equal(modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// modTime returns the modification time of file, or the zero time if it
+// does not exist.
+func modTime(file string) (time.Time, error) {
+	info, err := os.Stat(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlPrefix marks an apigen:api config written in YAML instead of JSON.
+const yamlPrefix = "yaml:"
+
+// decodeConfig decodes the config of an apigen:api annotation into v.
+// Configs are JSON, unless they start with yamlPrefix, in which case the
+// rest is a YAML mapping (see parseYAMLMapping) decoded like the
+// equivalent JSON object.
+func decodeConfig(config string, v interface{}) error {
+	if mapping, ok := strings.CutPrefix(strings.TrimSpace(config), yamlPrefix); ok {
+		values, err := parseYAMLMapping(mapping)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		config = string(data)
+	}
+	return json.Unmarshal([]byte(config), v)
+}
+
+// yamlKeyRe matches the start of a "key:" entry of a mapping, with a
+// plain or quoted key.
+var yamlKeyRe = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*|"[^"]*"|'[^']*')\s*:`)
+
+// parseYAMLMapping parses a YAML document holding a mapping. As a
+// shorthand, a single line that is not a flow mapping holds "key: value"
+// entries separated by commas followed by another key, so plain values
+// like GET,POST are kept whole.
+func parseYAMLMapping(text string) (map[string]interface{}, error) {
+	text = strings.TrimSpace(text)
+	if !strings.Contains(text, "\n") && !strings.HasPrefix(text, "{") {
+		text = strings.Join(splitYAMLEntries(text), "\n")
+	}
+
+	var doc interface{}
+	err := yaml.Unmarshal([]byte(text), &doc)
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return nil, fmt.Errorf("yaml: %s", strings.Join(typeErr.Errors, "; "))
+	} else if err != nil {
+		return nil, err
+	}
+	switch doc := doc.(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case map[string]interface{}:
+		return doc, nil
+	}
+	return nil, fmt.Errorf("yaml: expected a mapping of keys to values, got %q", text)
+}
+
+// splitYAMLEntries splits a line on the commas outside quoted strings,
+// flow collections and comments that are followed by another key.
+func splitYAMLEntries(line string) []string {
+	var entries []string
+	start := 0
+	depth := 0
+	var quote byte
+scan:
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		tokenStart := i == 0 || strings.IndexByte(" ,[{", line[i-1]) >= 0
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && tokenStart:
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			break scan
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0 && yamlKeyRe.MatchString(line[i+1:]):
+			entries = append(entries, strings.TrimSpace(line[start:i]))
+			start = i + 1
+		}
+	}
+	return append(entries, strings.TrimSpace(line[start:]))
+}
@@ -1,7 +1,10 @@
 package test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -21,17 +24,27 @@ var (
 )
 
 type Case struct {
-	Method string
-	Path   string
-	Query  string
-	Auth   bool
-	Status int
-	Result interface{}
+	Method      string
+	Path        string
+	Query       string
+	ContentType string
+	Auth        bool
+	AuthHeader  string
+	AuthScheme  string
+	Status      int
+	Result      interface{}
 }
 
 const (
-	ApiUserCreate  = "/user/create"
-	ApiUserProfile = "/user/profile"
+	ApiUserCreate     = example.URLMyApiCreate
+	ApiUserProfile    = example.URLMyApiProfile
+	ApiProductCreate  = example.URLProductApiCreate
+	ApiProductList    = example.URLProductApiList
+	ApiProductUpdate  = example.URLProductApiUpdate
+	ApiProductDelete  = example.URLProductApiDelete
+	ApiProductArchive = example.URLProductApiArchive
+	ApiProductStock   = example.URLProductApiStock
+	ApiProductReview  = example.URLProductApiReview
 )
 
 type CR map[string]interface{}
@@ -57,7 +70,7 @@ func TestMain(m *testing.M) {
 	}
 
 	// Run the generator
-	genCmd := exec.Command("./generator", "example/api.go", "example/generated_api.go")
+	genCmd := exec.Command("./generator", "-client", "example/client/generated_client.go", "-mocks", "-cors", "*", "-introspect", "-healthz", "-xml", "-gzip", "example/api.go", "example/generated_api.go")
 	genCmd.Stdout = os.Stdout
 	genCmd.Stderr = os.Stderr
 	err = genCmd.Run()
@@ -119,6 +132,30 @@ func TestMyApi(t *testing.T) {
 			Query:  "",
 			Status: http.StatusBadRequest,
 			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "login must be not empty",
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=%20rvasily%09",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        42,
+					"login":     "rvasily",
+					"full_name": "Vasily Romanov",
+					"status":    20,
+				},
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=%20%20",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
 				"error": "login must be not empty",
 			},
 		},
@@ -127,107 +164,1196 @@ func TestMyApi(t *testing.T) {
 			Query:  "login=bad_user",
 			Status: http.StatusInternalServerError,
 			Result: CR{
-				"error": "bad user",
+				"error": "bad user",
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=panic_user",
+			Status: http.StatusInternalServerError,
+			Result: CR{
+				"error": "internal server error",
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=not_exist_user",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "user not exist",
+			},
+		},
+		{
+			Path:   "/user/unknown/method",
+			Query:  "login=not_exist_user",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "unknown method",
+			},
+		},
+		{
+			Path:   "/user/rvasily",
+			Query:  "login=not_exist_user",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        42,
+					"login":     "rvasily",
+					"full_name": "Vasily Romanov",
+					"status":    20,
+				},
+			},
+		},
+		{
+			Path:   "/user/not_exist_user",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "user not exist",
+			},
+		},
+		{
+			Path:   "/user/",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "unknown method",
+			},
+		},
+		{
+			Path:   ApiUserCreate,
+			Method: http.MethodPost,
+			Query:  "login=mr.moderator&age=32&status=moderator&full_name=Ivan_Ivanov",
+			Status: http.StatusOK,
+			Auth:   true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id": 43,
+				},
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=mr.moderator",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        43,
+					"login":     "mr.moderator",
+					"full_name": "Ivan_Ivanov",
+					"status":    10,
+				},
+			},
+		},
+		{
+			Path:   ApiUserCreate,
+			Method: http.MethodGet,
+			Query:  "login=mr.moderator&age=32&status=moderator&full_name=GetMethod",
+			Status: http.StatusNotAcceptable,
+			Auth:   true,
+			Result: CR{
+				"error": "bad method",
+			},
+		},
+		{
+			Path:   ApiUserCreate,
+			Method: http.MethodPost,
+			Query:  "any_params=123",
+			Status: http.StatusForbidden,
+			Auth:   false,
+			Result: CR{
+				"error": "unauthorized",
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       `{"login": "mr.json.user", "age": 21, "status": "admin", "full_name": "Json User"}`,
+			ContentType: "application/json",
+			Status:      http.StatusOK,
+			Auth:        true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id": 44,
+				},
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=mr.json.user",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        44,
+					"login":     "mr.json.user",
+					"full_name": "Json User",
+					"status":    20,
+				},
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       `{"login": "short", "age": 21}`,
+			ContentType: "application/json",
+			Status:      http.StatusBadRequest,
+			Auth:        true,
+			Result: CR{
+				"code":  "INVALID_LOGIN",
+				"error": "login len must be >= 10",
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       `{"login": "Administrator", "age": 21}`,
+			ContentType: "application/json",
+			Status:      http.StatusBadRequest,
+			Auth:        true,
+			Result: CR{
+				"code":  "INVALID_LOGIN",
+				"error": "login Administrator is reserved",
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       `{"login": "mr.json.user2", "age": 200}`,
+			ContentType: "application/json",
+			Status:      http.StatusBadRequest,
+			Auth:        true,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "age must be <= 128",
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       `{"login": `,
+			ContentType: "application/json",
+			Status:      http.StatusBadRequest,
+			Auth:        true,
+			Result: CR{
+				"error": "invalid json body",
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       "login=mr.plain.text&age=21",
+			ContentType: "text/plain",
+			Status:      http.StatusUnsupportedMediaType,
+			Auth:        true,
+			Result: CR{
+				"error": "unsupported content type",
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       "login=mr.form.charset&age=21",
+			ContentType: "application/x-www-form-urlencoded; charset=utf-8",
+			Status:      http.StatusOK,
+			Auth:        true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id": 45,
+				},
+			},
+		},
+		{
+			// name is an alias of full_name
+			Path:   ApiUserCreate,
+			Method: http.MethodPost,
+			Query:  "login=mr.old.client&name=Old_Client",
+			Status: http.StatusOK,
+			Auth:   true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id": 46,
+				},
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=mr.old.client",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        46,
+					"login":     "mr.old.client",
+					"full_name": "Old_Client",
+					"status":    0,
+				},
+			},
+		},
+		{
+			// The parameter name takes precedence over its aliases, which are
+			// tried in order
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       `{"login": "mr.new.client", "fullname": "Second", "name": "First", "full_name": "New Client"}`,
+			ContentType: "application/json",
+			Status:      http.StatusOK,
+			Auth:        true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id": 47,
+				},
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=mr.new.client",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        47,
+					"login":     "mr.new.client",
+					"full_name": "New Client",
+					"status":    0,
+				},
+			},
+		},
+		{
+			Path:   ApiUserCreate,
+			Method: http.MethodPost,
+			Query:  "login=mr.alias.order&fullname=Second&name=First",
+			Status: http.StatusOK,
+			Auth:   true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id": 48,
+				},
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=mr.alias.order",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        48,
+					"login":     "mr.alias.order",
+					"full_name": "First",
+					"status":    0,
+				},
+			},
+		},
+		{
+			Path:        ApiUserCreate,
+			Method:      http.MethodPost,
+			Query:       "--form\r\nContent-Disposition: form-data; name=\"login\"\r\n\r\nmr.multipart\r\n--form\r\nContent-Disposition: form-data; name=\"full_name\"\r\n\r\nMulti Part\r\n--form--\r\n",
+			ContentType: "multipart/form-data; boundary=form",
+			Status:      http.StatusOK,
+			Auth:        true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id": 49,
+				},
+			},
+		},
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=mr.multipart",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        49,
+					"login":     "mr.multipart",
+					"full_name": "Multi Part",
+					"status":    0,
+				},
+			},
+		},
+		// Add more test cases as needed
+	}
+
+	runTests(t, ts, cases)
+}
+
+func TestOtherApi(t *testing.T) {
+	ts := httptest.NewServer(example.NewOtherApi())
+	defer ts.Close()
+
+	cases := []Case{
+		{
+			Path:   ApiUserCreate,
+			Method: http.MethodPost,
+			Query:  "username=I3apBap&level=1&class=barbarian&account_name=Vasily",
+			Status: http.StatusBadRequest,
+			Auth:   true,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "class must be one of [warrior, sorcerer, rouge]",
+			},
+		},
+		{
+			Path:   ApiUserCreate,
+			Method: http.MethodPost,
+			Query:  "username=I3apBap&level=1&class=Warrior&account_name=Vasily",
+			Status: http.StatusBadRequest,
+			Auth:   true,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "class must be one of [warrior, sorcerer, rouge]",
+			},
+		},
+		{
+			Path:   ApiUserCreate,
+			Method: http.MethodPost,
+			Query:  "username=I3apBap&level=1&class=warrior&account_name=Vasily",
+			Status: http.StatusOK,
+			Auth:   true,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        12,
+					"login":     "I3apBap",
+					"full_name": "Vasily",
+					"level":     1,
+				},
+			},
+		},
+	}
+
+	runTests(t, ts, cases)
+}
+
+func TestProductApi(t *testing.T) {
+	ts := httptest.NewServer(example.NewProductApi())
+	defer ts.Close()
+
+	cases := []Case{
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&code=abc&owner=owner@example.com&title=abc",
+			Status: http.StatusCreated,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "abc",
+					"owner":  "owner@example.com",
+					"title":  "abc",
+					"stock":  0,
+					"active": true,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=abc-123&owner=owner@example.com",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": `sku must match pattern ^[A-Z]{3}-\d+$`,
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&code=abcde&owner=owner@example.com",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": `code must be 2 to 4 "lowercase" letters, e.g. abc`,
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "owner must be not empty",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=not-an-email",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "owner must be a valid email",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=ab",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "title len must be >= 3",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abcdefghi",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "title len must be <= 8",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&stock=2",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "stock must be >= 3",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&stock=9",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "stock must be <= 8",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&active=off",
+			Status: http.StatusCreated,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "owner@example.com",
+					"title":  "abc",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&active=0",
+			Status: http.StatusCreated,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "owner@example.com",
+					"title":  "abc",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&active=maybe",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "active must be bool",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&price=19.99",
+			Status: http.StatusCreated,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "owner@example.com",
+					"title":  "abc",
+					"stock":  0,
+					"active": true,
+					"price":  19.99,
+				},
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&price=0.001",
+			Status: http.StatusCreated,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "owner@example.com",
+					"title":  "abc",
+					"stock":  0,
+					"active": true,
+					"price":  0.001,
+				},
+			},
+		},
+		{
+			// min_ex excludes the bound itself
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&price=0",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "price must be > 0",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&price=-0.5",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "price must be > 0",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&price=10000",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "price must be <= 9999.99",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abc&price=cheap",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "INVALID_PRODUCT",
+				"error": "price must be float",
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&limit=10&offset=20",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"owner":  "owner@example.com",
+					"sort":   "name",
+					"limit":  10,
+					"offset": 20,
+				},
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&sort=PRICE",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"owner":  "owner@example.com",
+					"sort":   "price",
+					"limit":  20,
+					"offset": 0,
+				},
+			},
+		},
+		{
+			// Defaults only apply to absent parameters, not to empty ones
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&sort=&limit=",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"owner":  "owner@example.com",
+					"sort":   "",
+					"limit":  0,
+					"offset": 0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&sort=date",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "sort must be one of [name, price]",
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&since=2024-02-29&until=2024-03-31",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"owner":  "owner@example.com",
+					"sort":   "name",
+					"limit":  20,
+					"offset": 0,
+					"since":  "2024-02-29",
+					"until":  "2024-03-31",
+				},
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&since=2023-02-29",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "since must be a date in the layout 2006-01-02",
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&until=31.03.2024",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "until must be a date in the layout 2006-01-02",
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&limit=101",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "limit must be <= 100",
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&status=2",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"owner":  "owner@example.com",
+					"sort":   "name",
+					"limit":  20,
+					"offset": 0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&status=3",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "status must be one of [0, 1, 2]",
+			},
+		},
+		{
+			// Enum values are compared numerically
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&status=01",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"owner":  "owner@example.com",
+					"sort":   "name",
+					"limit":  20,
+					"offset": 0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductList,
+			Query:  "owner=owner@example.com&limit=15",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "limit must be a multiple of 10",
+			},
+		},
+		{
+			Path:   ApiProductCreate,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&owner=owner@example.com&title=abcdefgh&stock=8",
+			Status: http.StatusCreated,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "owner@example.com",
+					"title":  "abcdefgh",
+					"stock":  8,
+					"active": true,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&stock=5",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  5,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&on_sale=maybe",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "on_sale must be bool",
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&on_sale=true",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "discount_code must be not empty when on_sale is true",
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&on_sale=true&discount_code=SPRING",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&on_sale=false",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&revision=f47ac10b-58cc-4372-A567-0e02b2c3d479",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&revision=",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&revision=not-a-uuid",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "revision must be a valid UUID",
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&revision=f47ac10b58cc-4372-a567-0e02b2c3d4790",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "revision must be a valid UUID",
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=ABC-123&revision=g47ac10b-58cc-4372-a567-0e02b2c3d479",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "revision must be a valid UUID",
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPut,
+			Query:  "sku=abc",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": `sku must match pattern ^[A-Z]{3}-\d+$`,
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodPatch,
+			Query:  "sku=ABC-123",
+			Status: http.StatusNotAcceptable,
+			Result: CR{
+				"error": "bad method",
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Method: http.MethodDelete,
+			Query:  "sku=ABC-123",
+			Status: http.StatusNotAcceptable,
+			Result: CR{
+				"error": "bad method",
+			},
+		},
+		{
+			Path:   ApiProductUpdate,
+			Query:  "sku=ABC-123",
+			Status: http.StatusNotAcceptable,
+			Result: CR{
+				"error": "bad method",
+			},
+		},
+		{
+			Path:       ApiProductDelete,
+			Method:     http.MethodDelete,
+			Query:      "sku=ABC-123",
+			Auth:       true,
+			AuthHeader: "X-Api-Key",
+			Status:     http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:   ApiProductDelete,
+			Method: http.MethodDelete,
+			Query:  "sku=ABC-123",
+			Auth:   true,
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "unauthorized",
+			},
+		},
+		{
+			Path:       ApiProductArchive,
+			Method:     http.MethodPost,
+			Query:      "sku=ABC-123",
+			Auth:       true,
+			AuthHeader: "Authorization",
+			AuthScheme: "bearer",
+			Status:     http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  0,
+					"active": false,
+					"price":  0,
+				},
+			},
+		},
+		{
+			Path:       ApiProductArchive,
+			Method:     http.MethodPost,
+			Query:      "sku=ABC-123",
+			Auth:       true,
+			AuthHeader: "Authorization",
+			Status:     http.StatusForbidden,
+			Result: CR{
+				"error": "unauthorized",
+			},
+		},
+		{
+			Path:       ApiProductArchive,
+			Method:     http.MethodPost,
+			Query:      "sku=ABC-000",
+			Auth:       true,
+			AuthHeader: "Authorization",
+			AuthScheme: "bearer",
+			Status:     http.StatusNotFound,
+			Result: CR{
+				"error": "archive ABC-000: product not found",
+			},
+		},
+		{
+			Path:   ApiProductArchive,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123",
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "unauthorized",
+			},
+		},
+		{
+			Path:   ApiProductStock,
+			Query:  "sku=ABC-123&delay=0",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"sku":    "ABC-123",
+					"code":   "",
+					"owner":  "",
+					"title":  "",
+					"stock":  42,
+					"active": false,
+					"price":  0,
+				},
 			},
 		},
 		{
-			Path:   ApiUserProfile,
-			Query:  "login=not_exist_user",
-			Status: http.StatusNotFound,
+			Path:   ApiProductStock,
+			Query:  "sku=ABC-123&warehouse=5000000000",
+			Status: http.StatusOK,
 			Result: CR{
-				"error": "user not exist",
+				"error": "",
+				"response": CR{
+					"sku":       "ABC-123",
+					"code":      "",
+					"owner":     "",
+					"title":     "",
+					"stock":     42,
+					"active":    false,
+					"price":     0,
+					"warehouse": 5000000000,
+				},
 			},
 		},
 		{
-			Path:   "/user/unknown",
-			Query:  "login=not_exist_user",
-			Status: http.StatusNotFound,
+			Path:   ApiProductStock,
+			Query:  "sku=ABC-123&warehouse=-1",
+			Status: http.StatusBadRequest,
 			Result: CR{
-				"error": "unknown method",
+				"code":  "VALIDATION_ERROR",
+				"error": "warehouse must be uint64",
 			},
 		},
 		{
-			Path:   ApiUserCreate,
+			// 2^31 overflows int32
+			Path:   ApiProductStock,
+			Query:  "sku=ABC-123&delay=2147483648",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "delay must be int32",
+			},
+		},
+		{
+			Path:   ApiProductStock,
+			Query:  "sku=ABC-123&delay=500",
+			Status: http.StatusGatewayTimeout,
+			Result: CR{
+				"error": "context deadline exceeded",
+			},
+		},
+		{
+			Path:   ApiProductStock,
+			Query:  "sku=ABC-123&delay=999",
+			Status: http.StatusGatewayTimeout,
+			Result: CR{
+				"error": "context deadline exceeded",
+			},
+		},
+		{
+			// max_ex excludes the bound itself
+			Path:   ApiProductStock,
+			Query:  "sku=ABC-123&delay=1000",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"code":  "VALIDATION_ERROR",
+				"error": "delay must be < 1000",
+			},
+		},
+		{
+			Path:   ApiProductReview,
 			Method: http.MethodPost,
-			Query:  "login=mr.moderator&age=32&status=moderator&full_name=Ivan_Ivanov",
+			Query:  "sku=ABC-123&rating=5&text=great",
 			Status: http.StatusOK,
-			Auth:   true,
 			Result: CR{
 				"error": "",
 				"response": CR{
-					"id": 43,
+					"sku":    "ABC-123",
+					"rating": 5,
+					"text":   "great",
 				},
 			},
 		},
 		{
-			Path:   ApiUserProfile,
-			Query:  "login=mr.moderator",
-			Status: http.StatusOK,
+			Path:   ApiProductReview,
+			Method: http.MethodPost,
+			Query:  "rating=9&text=great",
+			Status: http.StatusBadRequest,
 			Result: CR{
-				"error": "",
-				"response": CR{
-					"id":        43,
-					"login":     "mr.moderator",
-					"full_name": "Ivan_Ivanov",
-					"status":    10,
+				"errors": []string{
+					"sku must be not empty",
+					"rating must be <= 5",
 				},
 			},
 		},
 		{
-			Path:   ApiUserCreate,
-			Method: http.MethodGet,
-			Query:  "login=mr.moderator&age=32&status=moderator&full_name=GetMethod",
-			Status: http.StatusNotAcceptable,
-			Auth:   true,
+			Path:   ApiProductReview,
+			Method: http.MethodPost,
+			Query:  "sku=ABC-123&rating=5&text=" + strings.Repeat("a", 2048),
+			Status: http.StatusRequestEntityTooLarge,
 			Result: CR{
-				"error": "bad method",
+				"error": "request body too large",
 			},
 		},
 		{
-			Path:   ApiUserCreate,
-			Method: http.MethodPost,
-			Query:  "any_params=123",
-			Status: http.StatusForbidden,
-			Auth:   false,
+			Path:        ApiProductReview,
+			Method:      http.MethodPost,
+			Query:       `{"sku": "ABC-123", "text": "` + strings.Repeat("a", 2048) + `"}`,
+			ContentType: "application/json",
+			Status:      http.StatusRequestEntityTooLarge,
 			Result: CR{
-				"error": "unauthorized",
+				"error": "request body too large",
 			},
 		},
-		// Add more test cases as needed
 	}
 
 	runTests(t, ts, cases)
 }
 
-func TestOtherApi(t *testing.T) {
-	ts := httptest.NewServer(example.NewOtherApi())
+func TestRegisterRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	example.RegisterMyApiRoutes(mux, example.NewMyApi())
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "ok"}`))
+	})
+
+	ts := httptest.NewServer(mux)
 	defer ts.Close()
 
 	cases := []Case{
+		{
+			Path:   ApiUserProfile,
+			Query:  "login=rvasily",
+			Status: http.StatusOK,
+			Result: CR{
+				"error": "",
+				"response": CR{
+					"id":        42,
+					"login":     "rvasily",
+					"full_name": "Vasily Romanov",
+					"status":    20,
+				},
+			},
+		},
+		{
+			Path:   "/user/not_exist_user",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "user not exist",
+			},
+		},
 		{
 			Path:   ApiUserCreate,
-			Method: http.MethodPost,
-			Query:  "username=I3apBap&level=1&class=barbarian&account_name=Vasily",
-			Status: http.StatusBadRequest,
+			Query:  "login=mr.routes",
 			Auth:   true,
+			Status: http.StatusNotAcceptable,
 			Result: CR{
-				"error": "class must be one of [warrior, sorcerer, rouge]",
+				"error": "bad method",
 			},
 		},
 		{
-			Path:   ApiUserCreate,
-			Method: http.MethodPost,
-			Query:  "username=I3apBap&level=1&class=warrior&account_name=Vasily",
+			Path:   "/health",
 			Status: http.StatusOK,
-			Auth:   true,
 			Result: CR{
-				"error": "",
-				"response": CR{
-					"id":        12,
-					"login":     "I3apBap",
-					"full_name": "Vasily",
-					"level":     1,
-				},
+				"status": "ok",
 			},
 		},
 	}
@@ -235,6 +1361,256 @@ func TestOtherApi(t *testing.T) {
 	runTests(t, ts, cases)
 }
 
+func TestHealthz(t *testing.T) {
+	mux := http.NewServeMux()
+	example.RegisterMyApiRoutes(mux, example.NewMyApi())
+	example.RegisterProductApiRoutes(mux, example.NewProductApi())
+
+	for _, handler := range []http.Handler{example.NewMyApi(), mux} {
+		ts := httptest.NewServer(handler)
+		runTests(t, ts, []Case{
+			{
+				Path:   "/healthz",
+				Status: http.StatusOK,
+				Result: CR{
+					"status": "ok",
+				},
+			},
+		})
+		ts.Close()
+	}
+}
+
+func TestXML(t *testing.T) {
+	mux := http.NewServeMux()
+	example.RegisterMyApiRoutes(mux, example.NewMyApi())
+	example.RegisterProductApiRoutes(mux, example.NewProductApi())
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	profile := example.URLMyApiProfile + "?login="
+	cases := []struct {
+		Method      string
+		URL         string
+		ContentType string
+		Accept      string
+		Status      int
+		Type        string
+		Body        string
+	}{
+		{
+			URL:    profile + "rvasily",
+			Accept: "application/xml",
+			Status: http.StatusOK,
+			Type:   "application/xml",
+			Body:   xml.Header + "<envelope><response><id>42</id><login>rvasily</login><full_name>Vasily Romanov</full_name><status>20</status></response></envelope>",
+		},
+		{
+			URL:    profile + "bad_user",
+			Accept: "text/xml;q=0.9",
+			Status: http.StatusInternalServerError,
+			Type:   "application/xml",
+			Body:   xml.Header + "<envelope><error>bad user</error></envelope>",
+		},
+		{
+			URL:    profile + "rvasily",
+			Accept: "application/json, application/xml",
+			Status: http.StatusOK,
+			Body:   "{\"error\":\"\",\"response\":{\"id\":42,\"login\":\"rvasily\",\"full_name\":\"Vasily Romanov\",\"status\":20}}\n",
+		},
+		// The media range with the highest q-value wins, whatever the order
+		{
+			URL:    profile + "bad_user",
+			Accept: "application/json;q=0.5, application/xml",
+			Status: http.StatusInternalServerError,
+			Type:   "application/xml",
+			Body:   xml.Header + "<envelope><error>bad user</error></envelope>",
+		},
+		{
+			URL:    profile + "bad_user",
+			Accept: "text/html, application/xml;q=0.8, */*;q=0.9",
+			Status: http.StatusInternalServerError,
+			Body:   "{\"error\": \"bad user\"}\n",
+		},
+		{
+			URL:    profile + "bad_user",
+			Accept: "application/xml;q=0, text/html",
+			Status: http.StatusInternalServerError,
+			Body:   "{\"error\": \"bad user\"}\n",
+		},
+		// Requests rejected before the API method is called
+		{
+			URL:    profile,
+			Accept: "application/xml",
+			Status: http.StatusBadRequest,
+			Type:   "application/xml",
+			Body:   xml.Header + "<envelope><error>login must be not empty</error><code>VALIDATION_ERROR</code></envelope>",
+		},
+		{
+			Method: http.MethodPost,
+			URL:    example.URLMyApiCreate,
+			Accept: "application/xml",
+			Status: http.StatusForbidden,
+			Type:   "application/xml",
+			Body:   xml.Header + "<envelope><error>unauthorized</error></envelope>",
+		},
+		{
+			Method: http.MethodDelete,
+			URL:    profile + "rvasily",
+			Accept: "application/xml",
+			Status: http.StatusNotAcceptable,
+			Type:   "application/xml",
+			Body:   xml.Header + "<envelope><error>bad method</error></envelope>",
+		},
+		{
+			Method:      http.MethodPost,
+			URL:         example.URLMyApiProfile,
+			ContentType: "text/plain",
+			Accept:      "application/xml",
+			Status:      http.StatusUnsupportedMediaType,
+			Type:        "application/xml",
+			Body:        xml.Header + "<envelope><error>unsupported content type</error></envelope>",
+		},
+		{
+			Method: http.MethodPost,
+			URL:    example.URLProductApiReview + "?rating=9",
+			Accept: "application/xml",
+			Status: http.StatusBadRequest,
+			Type:   "application/xml",
+			Body:   xml.Header + "<envelope><errors>sku must be not empty</errors><errors>rating must be &lt;= 5</errors></envelope>",
+		},
+		{
+			URL:    profile,
+			Status: http.StatusBadRequest,
+			Body:   "{\"error\": \"login must be not empty\", \"code\": \"VALIDATION_ERROR\"}",
+		},
+	}
+	for _, item := range cases {
+		method := item.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		req, _ := http.NewRequest(method, ts.URL+item.URL, nil)
+		if item.ContentType != "" {
+			req.Header.Set("Content-Type", item.ContentType)
+		}
+		req.Header.Set("Accept", item.Accept)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.Status {
+			t.Errorf("%s %s %s: expected status %d, got %d", method, item.URL, item.Accept, item.Status, resp.StatusCode)
+		}
+		if item.Type != "" && resp.Header.Get("Content-Type") != item.Type {
+			t.Errorf("%s %s %s: expected Content-Type %s, got %s", method, item.URL, item.Accept, item.Type, resp.Header.Get("Content-Type"))
+		}
+		if strings.TrimSpace(string(body)) != strings.TrimSpace(item.Body) {
+			t.Errorf("%s %s %s: expected body %s, got %s", method, item.URL, item.Accept, item.Body, body)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	ts := httptest.NewServer(example.NewMyApi())
+	defer ts.Close()
+
+	get := func(encoding string) (*http.Response, []byte) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+example.URLMyApiProfile+"?login=rvasily", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, body
+	}
+	expected := "{\"error\":\"\",\"response\":{\"id\":42,\"login\":\"rvasily\",\"full_name\":\"Vasily Romanov\",\"status\":20}}\n"
+
+	// The profile is smaller than GzipMinSize
+	resp, body := get("gzip")
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("expected a small response to be uncompressed, got Content-Encoding %s", resp.Header.Get("Content-Encoding"))
+	}
+	if string(body) != expected {
+		t.Errorf("expected body %s, got %s", expected, body)
+	}
+
+	minSize := example.GzipMinSize
+	example.GzipMinSize = 1
+	defer func() { example.GzipMinSize = minSize }()
+
+	resp, body = get("deflate, gzip;q=0.8")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected Content-Encoding gzip, got %q", resp.Header.Get("Content-Encoding"))
+	}
+	if resp.Header.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected Vary Accept-Encoding, got %q", resp.Header.Get("Vary"))
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("cant read gzip: %v", err)
+	}
+	body, err = ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("cant decompress: %v", err)
+	}
+	if string(body) != expected {
+		t.Errorf("expected body %s, got %s", expected, body)
+	}
+
+	resp, body = get("gzip;q=0")
+	if resp.Header.Get("Content-Encoding") != "" || string(body) != expected {
+		t.Errorf("expected an uncompressed response, got %q: %s", resp.Header.Get("Content-Encoding"), body)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	ts := httptest.NewServer(example.NewMyApi())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodOptions, ts.URL+ApiUserCreate, nil)
+	if err != nil {
+		t.Fatalf("cant create request: %v", err)
+	}
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected http status %v, got %v", http.StatusNoContent, resp.StatusCode)
+	}
+	for header, expected := range map[string]string{
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Methods": "POST",
+		"Access-Control-Allow-Headers": "Content-Type, X-Auth",
+	} {
+		if got := resp.Header.Get(header); got != expected {
+			t.Errorf("expected %s %q, got %q", header, expected, got)
+		}
+	}
+
+	resp, err = client.Get(ts.URL + ApiUserProfile + "?login=rvasily")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected Access-Control-Allow-Origin %q, got %q", "*", got)
+	}
+}
+
 func runTests(t *testing.T, ts *httptest.Server, cases []Case) {
 	for idx, item := range cases {
 		var (
@@ -246,10 +1622,14 @@ func runTests(t *testing.T, ts *httptest.Server, cases []Case) {
 
 		caseName := fmt.Sprintf("case %d: [%s] %s %s", idx, item.Method, item.Path, item.Query)
 
-		if item.Method == http.MethodPost {
+		if item.Method == http.MethodPost || item.Method == http.MethodPut || item.Method == http.MethodPatch {
 			reqBody := strings.NewReader(item.Query)
 			req, err = http.NewRequest(item.Method, ts.URL+item.Path, reqBody)
-			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			contentType := item.ContentType
+			if contentType == "" {
+				contentType = "application/x-www-form-urlencoded"
+			}
+			req.Header.Add("Content-Type", contentType)
 		} else {
 			req, err = http.NewRequest(item.Method, ts.URL+item.Path+"?"+item.Query, nil)
 		}
@@ -265,8 +1645,15 @@ func runTests(t *testing.T, ts *httptest.Server, cases []Case) {
 			} else {
 				authKey = os.Getenv("MY_API_KEY")
 			}
-			req.Header.Add("X-Auth", authKey)
-			fmt.Printf("Setting X-Auth header to: %s for path: %s\n", authKey, item.Path) // Debug print
+			authHeader := item.AuthHeader
+			if authHeader == "" {
+				authHeader = "X-Auth"
+			}
+			if item.AuthScheme == "bearer" {
+				authKey = "Bearer " + authKey
+			}
+			req.Header.Add(authHeader, authKey)
+			fmt.Printf("Setting %s header to: %s for path: %s\n", authHeader, authKey, item.Path) // Debug print
 		}
 
 		resp, err := client.Do(req)
@@ -297,3 +1684,117 @@ func runTests(t *testing.T, ts *httptest.Server, cases []Case) {
 		}
 	}
 }
+
+func TestWhoami(t *testing.T) {
+	ts := httptest.NewServer(example.NewMyApi())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+example.URLMyApiWhoami+"?login=rvasily", nil)
+	if err != nil {
+		t.Fatalf("cant create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "whoami-test")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Response example.Client `json:"response"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("cant unpack json: %v", err)
+	}
+	// The method reads the address and headers from the *http.Request
+	expected := example.Client{Login: "rvasily", Addr: result.Response.Addr, UserAgent: "whoami-test"}
+	if result.Response != expected || !strings.HasPrefix(result.Response.Addr, "127.0.0.1:") {
+		t.Errorf("expected %+v from 127.0.0.1, got %+v", expected, result.Response)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	expected := map[string]example.RouteInfo{
+		example.URLMyApiProfile: {Name: "Profile", Methods: []string{"GET", "POST"}, Auth: false, Params: []string{"login"}},
+		example.URLMyApiCreate:  {Name: "Create", Methods: []string{"POST"}, Auth: true, Params: []string{"login", "full_name", "status", "age"}},
+		example.URLMyApiUser:    {Name: "User", Methods: []string{"GET"}, Auth: false, Params: []string{"login"}},
+		example.URLMyApiWhoami:  {Name: "Whoami", Methods: []string{"GET"}, Auth: false, Params: []string{"login"}},
+	}
+	if !reflect.DeepEqual(example.MyApiRoutes, expected) {
+		t.Errorf("expected routes %+v, got %+v", expected, example.MyApiRoutes)
+	}
+
+	for url, route := range example.ProductApiRoutes {
+		if len(route.Methods) != 1 {
+			t.Errorf("%s: expected one method, got %v", url, route.Methods)
+		}
+	}
+	if route := example.ProductApiRoutes[example.URLProductApiArchive]; !route.Auth {
+		t.Errorf("expected %s to require auth", example.URLProductApiArchive)
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	ts := httptest.NewServer(example.NewMyApi())
+	defer ts.Close()
+
+	resp, err := client.Get(ts.URL + "/_introspect")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected http status %v, got %v", http.StatusOK, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected Content-Type %q, got %q", "application/json", got)
+	}
+
+	var doc struct {
+		Methods []struct {
+			Name        string   `json:"name"`
+			Url         string   `json:"url"`
+			HTTPMethods []string `json:"http_methods"`
+			Auth        bool     `json:"auth"`
+			Params      []struct {
+				Name     string   `json:"name"`
+				Type     string   `json:"type"`
+				Required bool     `json:"required"`
+				Min      *float64 `json:"min"`
+				Enum     []string `json:"enum"`
+				Default  string   `json:"default"`
+			} `json:"params"`
+		} `json:"methods"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	if err != nil {
+		t.Fatalf("cant unpack json: %v", err)
+	}
+
+	if len(doc.Methods) != 4 {
+		t.Fatalf("expected 4 methods, got %d", len(doc.Methods))
+	}
+	for _, method := range doc.Methods {
+		if method.Url != ApiUserCreate {
+			continue
+		}
+		if method.Name != "Create" || !method.Auth || !reflect.DeepEqual(method.HTTPMethods, []string{"POST"}) {
+			t.Errorf("unexpected description of %s: %+v", ApiUserCreate, method)
+		}
+		login := method.Params[0]
+		if login.Name != "login" || login.Type != "string" || !login.Required || login.Min == nil || *login.Min != 10 {
+			t.Errorf("unexpected login param: %+v", login)
+		}
+		for _, param := range method.Params {
+			if param.Name == "status" {
+				if param.Default != "user" || !reflect.DeepEqual(param.Enum, []string{"user", "moderator", "admin"}) {
+					t.Errorf("unexpected status param: %+v", param)
+				}
+			}
+		}
+		return
+	}
+	t.Errorf("method %s not described", ApiUserCreate)
+}
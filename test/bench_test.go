@@ -0,0 +1,80 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/notrightending/gonerator/example"
+)
+
+// Run with go test -bench . -benchmem ./test to see the time and the
+// allocations per request of the generated handlers.
+
+func BenchmarkProfile(b *testing.B) {
+	api := example.NewMyApi()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ApiUserProfile+"?login=rvasily", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+		}
+	}
+	reportRequestRate(b)
+}
+
+func BenchmarkProfileJSON(b *testing.B) {
+	api := example.NewMyApi()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, ApiUserProfile, strings.NewReader(`{"login": "rvasily"}`))
+		r.Header.Set("Content-Type", "application/json")
+		api.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			b.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+		}
+	}
+	reportRequestRate(b)
+}
+
+func BenchmarkCreate(b *testing.B) {
+	api := example.NewMyApi()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		body := "login=bench_user_" + strconv.Itoa(i) + "&full_name=Bench&status=moderator&age=30"
+		r := httptest.NewRequest(http.MethodPost, ApiUserCreate, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-Auth", "test_my_api_key")
+		api.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			b.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+		}
+	}
+	reportRequestRate(b)
+}
+
+func BenchmarkServeParallel(b *testing.B) {
+	api := example.NewMyApi()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ApiUserProfile+"?login=rvasily", nil))
+			if w.Code != http.StatusOK {
+				b.Errorf("expected status 200, got %d", w.Code)
+				return
+			}
+		}
+	})
+	reportRequestRate(b)
+}
+
+// reportRequestRate reports the requests per second served by the benchmark.
+func reportRequestRate(b *testing.B) {
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "req/s")
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/notrightending/gonerator/example"
+	apiclient "github.com/notrightending/gonerator/example/client"
+)
+
+func TestMyApiClient(t *testing.T) {
+	ts := httptest.NewServer(example.NewMyApi())
+	defer ts.Close()
+
+	ctx := context.Background()
+	c := apiclient.NewMyApiClient(ts.URL, os.Getenv("MY_API_KEY"))
+
+	user, err := c.Profile(ctx, example.ProfileParams{Login: "rvasily"})
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	if user.ID != 42 || user.FullName != "Vasily Romanov" {
+		t.Errorf("unexpected profile: %+v", user)
+	}
+
+	created, err := c.Create(ctx, example.CreateParams{
+		Login:  "mr.client.user",
+		Name:   "Client User",
+		Status: "moderator",
+		Age:    30,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.ID != 43 {
+		t.Errorf("expected id 43, got %d", created.ID)
+	}
+
+	user, err = c.User(ctx, example.UserParams{Login: "rvasily"})
+	if err != nil {
+		t.Fatalf("User failed: %v", err)
+	}
+	if user.ID != 42 {
+		t.Errorf("unexpected user: %+v", user)
+	}
+
+	_, err = c.Profile(ctx, example.ProfileParams{Login: "not_exist_user"})
+	var apiErr example.ApiError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusNotFound || apiErr.Error() != "user not exist" {
+		t.Errorf("expected not found ApiError, got %v", err)
+	}
+
+	c.AuthKey = "wrong_key"
+	_, err = c.Create(ctx, example.CreateParams{Login: "mr.client.user2"})
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusForbidden {
+		t.Errorf("expected forbidden ApiError, got %v", err)
+	}
+}
+
+func TestProductApiClientAuthHeader(t *testing.T) {
+	ts := httptest.NewServer(example.NewProductApi())
+	defer ts.Close()
+
+	ctx := context.Background()
+	c := apiclient.NewProductApiClient(ts.URL, os.Getenv("MY_API_KEY"))
+
+	product, err := c.Delete(ctx, example.ProductDeleteParams{Sku: "ABC-123"})
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if product.Sku != "ABC-123" {
+		t.Errorf("unexpected product: %+v", product)
+	}
+
+	_, err = c.Review(ctx, example.ProductReviewParams{Rating: 9})
+	var reviewErr example.ApiError
+	if !errors.As(err, &reviewErr) || reviewErr.Error() != "sku must be not empty; rating must be <= 5" {
+		t.Errorf("expected collected validation errors, got %v", err)
+	}
+
+	archived, err := c.Archive(ctx, example.ProductArchiveParams{Sku: "ABC-123"})
+	if err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if archived.Sku != "ABC-123" {
+		t.Errorf("unexpected product: %+v", archived)
+	}
+
+	c.AuthKey = "wrong_key"
+	_, err = c.Delete(ctx, example.ProductDeleteParams{Sku: "ABC-123"})
+	var apiErr example.ApiError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusForbidden {
+		t.Errorf("expected forbidden ApiError, got %v", err)
+	}
+}
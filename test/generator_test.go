@@ -0,0 +1,3973 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notrightending/gonerator/internal/generator"
+)
+
+func TestGenerateDir(t *testing.T) {
+	dir := t.TempDir()
+
+	src, err := os.ReadFile("example/api.go")
+	if err != nil {
+		t.Fatalf("cant read example api: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "api.go"), src, 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "types.go"), []byte("package example\n"), 0644)
+	if err != nil {
+		t.Fatalf("cant write types.go: %v", err)
+	}
+
+	err = generator.GenerateDir(dir, generator.DefaultOutPattern)
+	if err != nil {
+		t.Fatalf("GenerateDir failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "api_gen.go")); err != nil {
+		t.Errorf("expected api_gen.go to be generated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "types_gen.go")); !os.IsNotExist(err) {
+		t.Errorf("expected types_gen.go to be skipped, got err %v", err)
+	}
+}
+
+func TestGenerateDirOutputCollision(t *testing.T) {
+	dir := t.TempDir()
+	for name, receiver := range map[string]string{"first.go": "First", "second.go": "Second"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(apiSource("api", receiver, "/"+strings.ToLower(receiver))), 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", name, err)
+		}
+	}
+
+	err := generator.GenerateDir(dir, "handlers.go")
+	if err == nil || !strings.Contains(err.Error(), "the output pattern must contain {name}") {
+		t.Errorf("expected an output collision error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "handlers.go")); !os.IsNotExist(err) {
+		t.Errorf("expected handlers.go not to be written, got err %v", err)
+	}
+
+	_, err = generator.NewFileCache().GenerateDir(dir, "handlers.go", generator.Options{})
+	if err == nil || !strings.Contains(err.Error(), "the output pattern must contain {name}") {
+		t.Errorf("expected an output collision error in watch mode, got %v", err)
+	}
+}
+
+func TestGenerateDirMocks(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(apiSource("api", "Api", "/api")), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	for _, input := range []string{dir, filepath.Join(dir, "*.go")} {
+		out, err := exec.Command("./generator", "-mocks", input).CombinedOutput()
+		if err == nil || !strings.Contains(string(out), "-mocks cannot be used with a directory or glob input") {
+			t.Errorf("%s: expected -mocks to be rejected, got %v\n%s", input, err, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "api_gen.go")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be generated, got err %v", err)
+	}
+}
+
+func TestGenerateDirExclude(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile("example/api.go")
+	if err != nil {
+		t.Fatalf("cant read example api: %v", err)
+	}
+	files := map[string]string{
+		"api.go":              string(src),
+		"other/other.go":      apiSource("other", "Other", "/other"),
+		"other/other_gen.go":  apiSource("other", "Stale", "/stale"),
+		"other/other_test.go": apiSource("other", "Test", "/test"),
+		"other/skip_me.go":    apiSource("other", "Skip", "/skip"),
+		"other/skip_dup.go": `package other
+
+import "context"
+
+type Dup struct{}
+
+// apigen:api {"url": "/other"}
+func (srv *Other) Dup(ctx context.Context, in OtherParams) (*OtherItem, error) {
+	return nil, nil
+}
+
+// apigen:api {"url":
+func (srv *Dup) Broken(ctx context.Context, in OtherParams) (*OtherItem, error) {
+	return nil, nil
+}
+`,
+		"other/skip_syntax.go": "package other\n\nfunc broken( {\n",
+		"vendor/lib/lib.go":    apiSource("lib", "Lib", "/lib"),
+		"vendor/lib/extra.go":  apiSource("lib", "Extra", "/extra"),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("cant create dir for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("cant write %s: %v", name, err)
+		}
+	}
+
+	err = generator.GenerateDirWithOptions(dir, generator.DefaultOutPattern, generator.Options{Exclude: []string{"vendor", "skip_*.go"}})
+	if err != nil {
+		t.Fatalf("GenerateDirWithOptions failed: %v", err)
+	}
+	for _, name := range []string{"api_gen.go", "other/other_gen.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be generated: %v", name, err)
+		}
+	}
+	for _, name := range []string{"other/other_gen_gen.go", "other/other_test_gen.go", "other/skip_me_gen.go", "other/skip_dup_gen.go", "other/skip_syntax_gen.go", "vendor/lib/lib_gen.go", "vendor/lib/extra_gen.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be skipped, got err %v", name, err)
+		}
+	}
+	code, err := os.ReadFile(filepath.Join(dir, "other/other_gen.go"))
+	if err != nil {
+		t.Fatalf("cant read other_gen.go: %v", err)
+	}
+	for _, url := range []string{"/stale", "/test", "/skip", "Dup"} {
+		if strings.Contains(string(code), url) {
+			t.Errorf("expected other_gen.go not to route %s, got:\n%s", url, code)
+		}
+	}
+
+	err = generator.GenerateDirWithOptions(dir, generator.DefaultOutPattern, generator.Options{Exclude: []string{"["}})
+	if err == nil || !strings.Contains(err.Error(), `invalid exclude pattern "["`) {
+		t.Errorf("expected an invalid pattern error, got %v", err)
+	}
+
+	out, err := exec.Command("./generator", "-dump", "-exclude", "vendor", "-exclude", "other", dir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("generator -dump failed: %v\n%s", err, out)
+	}
+	if strings.Contains(string(out), "/other") || strings.Contains(string(out), "/lib") || !strings.Contains(string(out), "/user/profile") {
+		t.Errorf("expected only the methods of api.go to be dumped, got:\n%s", out)
+	}
+}
+
+// apiSource returns the source of a file of package pkg declaring the
+// receiver type receiver with one API method at url.
+func apiSource(pkg, receiver, url string) string {
+	return `package ` + pkg + `
+
+import "context"
+
+type ` + receiver + ` struct{}
+
+type ` + receiver + `Params struct {
+	Sku string ` + "`apivalidator:\"required\"`" + `
+}
+
+type ` + receiver + `Item struct{}
+
+// apigen:api {"url": "` + url + `"}
+func (srv *` + receiver + `) Get(ctx context.Context, in ` + receiver + `Params) (*` + receiver + `Item, error) {
+	return &` + receiver + `Item{}, nil
+}
+`
+}
+
+func TestGenerateFilesConcurrently(t *testing.T) {
+	dir := t.TempDir()
+
+	var inputFiles []string
+	for i := 0; i < 24; i++ {
+		sub := filepath.Join(dir, fmt.Sprintf("pkg%d", i%3))
+		err := os.MkdirAll(sub, 0755)
+		if err != nil {
+			t.Fatalf("cant create %s: %v", sub, err)
+		}
+		path := filepath.Join(sub, fmt.Sprintf("api%d.go", i))
+		err = os.WriteFile(path, []byte(apiSource(fmt.Sprintf("pkg%d", i%3), fmt.Sprintf("Api%d", i), fmt.Sprintf("/item%d/get", i))), 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", path, err)
+		}
+		inputFiles = append(inputFiles, path)
+	}
+
+	err := generator.GenerateFiles(inputFiles, generator.DefaultOutPattern, generator.Options{})
+	if err != nil {
+		t.Fatalf("GenerateFiles failed: %v", err)
+	}
+
+	// Every output must match the output of generating its input alone
+	for _, inputFile := range inputFiles {
+		outputFile := generator.OutputPath(inputFile, generator.DefaultOutPattern)
+		code, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("cant read output: %v", err)
+		}
+
+		expectedFile := filepath.Join(t.TempDir(), "expected.go")
+		err = generator.Generate(inputFile, expectedFile)
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		expected, err := os.ReadFile(expectedFile)
+		if err != nil {
+			t.Fatalf("cant read expected output: %v", err)
+		}
+		if !bytes.Equal(code, expected) {
+			t.Errorf("%s does not match the output of %s alone", outputFile, inputFile)
+		}
+	}
+
+	diff, err := generator.CheckFiles(inputFiles, generator.DefaultOutPattern, generator.Options{})
+	if err != nil || diff != "" {
+		t.Errorf("expected generated files to be up to date, got %v:\n%s", err, diff)
+	}
+}
+
+func TestGenerateFilesFirstError(t *testing.T) {
+	dir := t.TempDir()
+
+	var inputFiles []string
+	for i := 0; i < 16; i++ {
+		sub := filepath.Join(dir, fmt.Sprintf("pkg%d", i))
+		err := os.MkdirAll(sub, 0755)
+		if err != nil {
+			t.Fatalf("cant create %s: %v", sub, err)
+		}
+		// Every fourth file from the sixth on has an invalid tag
+		src := apiSource("example", "Api", "/item/get")
+		if i >= 5 && i%4 == 1 {
+			src = strings.Replace(src, "required", "min=ten", 1)
+		}
+		path := filepath.Join(sub, "api.go")
+		err = os.WriteFile(path, []byte(src), 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", path, err)
+		}
+		inputFiles = append(inputFiles, path)
+	}
+
+	for run := 0; run < 5; run++ {
+		err := generator.GenerateFiles(inputFiles, generator.DefaultOutPattern, generator.Options{})
+		if err == nil || !strings.Contains(err.Error(), filepath.Join("pkg5", "api.go")) {
+			t.Fatalf("expected the error of pkg5/api.go, got %v", err)
+		}
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": `package example
+
+import "context"
+
+type Api struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/a"}
+func (srv *Api) A(ctx context.Context, in AParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"b.go": `package example
+
+import "context"
+
+type BParams struct {
+	Sku string
+}
+
+// apigen:api {"url": "/b"}
+func (srv *Api) B(ctx context.Context, in BParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"types.go": `package example
+
+type AParams struct {
+	Sku string ` + "`apivalidator:\"required\"`" + `
+}
+`,
+	}
+	// Every write gets a later modification time, however coarse the clock
+	modTime := time.Now().Add(-time.Hour)
+	write := func(name, src string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(src), 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", name, err)
+		}
+		modTime = modTime.Add(time.Second)
+		err = os.Chtimes(path, modTime, modTime)
+		if err != nil {
+			t.Fatalf("cant set the modification time of %s: %v", name, err)
+		}
+	}
+	for name, src := range files {
+		write(name, src)
+	}
+
+	cache := generator.NewFileCache()
+	generate := func(expected ...string) {
+		t.Helper()
+		written, err := cache.GenerateDir(dir, generator.DefaultOutPattern, generator.Options{})
+		if err != nil {
+			t.Fatalf("GenerateDir failed: %v", err)
+		}
+		for i := range expected {
+			expected[i] = filepath.Join(dir, expected[i])
+		}
+		if strings.Join(written, ",") != strings.Join(expected, ",") {
+			t.Errorf("expected %v to be written, got %v", expected, written)
+		}
+	}
+
+	generate("a_gen.go", "b_gen.go")
+	generate()
+
+	// A change of a struct only regenerates the outputs using it
+	write("types.go", strings.Replace(files["types.go"], "required", "min=3", 1))
+	generate("a_gen.go")
+	code, err := os.ReadFile(filepath.Join(dir, "a_gen.go"))
+	if err != nil {
+		t.Fatalf("cant read a_gen.go: %v", err)
+	}
+	if !strings.Contains(string(code), "sku len must be >= 3") {
+		t.Errorf("expected a_gen.go to use the new tag, got:\n%s", code)
+	}
+
+	// Changes that do not affect the API methods regenerate nothing
+	write("b.go", files["b.go"]+"\n// Unrelated comment\n")
+	generate()
+
+	// An error is reported once, and fixing it renders every output again,
+	// writing those that changed
+	write("b.go", files["b.go"]+"\nbroken\n")
+	_, err = cache.GenerateDir(dir, generator.DefaultOutPattern, generator.Options{})
+	if err == nil {
+		t.Errorf("expected a parse error")
+	}
+	generate()
+	write("b.go", strings.Replace(files["b.go"], `"/b"`, `"/b2"`, 1))
+	generate("b_gen.go")
+}
+
+func TestFileCacheChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.go")
+	cache := generator.NewFileCache()
+	changed := func(expected bool) {
+		t.Helper()
+		got, err := cache.Changed([]string{path})
+		if err != nil {
+			t.Fatalf("Changed failed: %v", err)
+		}
+		if got != expected {
+			t.Errorf("expected changed %v, got %v", expected, got)
+		}
+	}
+
+	changed(true)
+	changed(false)
+	err := os.WriteFile(path, []byte("package example\n"), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+	changed(true)
+	changed(false)
+	os.Remove(path)
+	changed(true)
+}
+
+func TestGeneratePackageCrossFileStructs(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"api.go": `package example
+
+import "context"
+
+type Api struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"types.go": `package example
+
+type GetParams struct {
+	Sku string ` + "`apivalidator:\"required\"`" + `
+}
+
+type Item struct{}
+`,
+	}
+	var inputFiles []string
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(src), 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", name, err)
+		}
+		inputFiles = append(inputFiles, path)
+	}
+
+	outputFile := filepath.Join(dir, "out.go")
+	err := generator.GeneratePackage(inputFiles, outputFile, generator.Options{})
+	if err != nil {
+		t.Fatalf("GeneratePackage failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	if !strings.Contains(string(code), `params.Sku = queryParams.Get("sku")`) {
+		t.Errorf("expected params from types.go to be parsed, got:\n%s", code)
+	}
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	dir := t.TempDir()
+	specFile := filepath.Join(dir, "openapi.json")
+
+	err := generator.GenerateWithOptions("example/api.go", filepath.Join(dir, "out.go"), generator.Options{
+		OpenAPIFile: specFile,
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions failed: %v", err)
+	}
+
+	data, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatalf("cant read spec: %v", err)
+	}
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Parameters []struct {
+				Name     string `json:"name"`
+				In       string `json:"in"`
+				Required bool   `json:"required"`
+			} `json:"parameters"`
+			Security []map[string][]string `json:"security"`
+		} `json:"paths"`
+	}
+	err = json.Unmarshal(data, &spec)
+	if err != nil {
+		t.Fatalf("cant unpack spec: %v", err)
+	}
+
+	if spec.OpenAPI != "3.0.3" {
+		t.Errorf("expected openapi 3.0.3, got %q", spec.OpenAPI)
+	}
+
+	profile := spec.Paths["/user/profile"]
+	if _, ok := profile["get"]; !ok {
+		t.Fatalf("expected GET /user/profile in spec, got %v", profile)
+	}
+	if _, ok := profile["post"]; !ok {
+		t.Errorf("expected POST /user/profile in spec, got %v", profile)
+	}
+	params := profile["get"].Parameters
+	if len(params) != 1 || params[0].Name != "login" || !params[0].Required {
+		t.Errorf("expected required login parameter, got %+v", params)
+	}
+	if len(profile["get"].Security) != 0 {
+		t.Errorf("expected /user/profile to be unauthenticated")
+	}
+
+	create := spec.Paths["/user/create"]
+	if _, ok := create["get"]; ok {
+		t.Errorf("expected /user/create to be POST only")
+	}
+	if len(create["post"].Security) == 0 {
+		t.Errorf("expected /user/create to require X-Auth")
+	}
+
+	user := spec.Paths["/user/{login}"]["get"].Parameters
+	if len(user) != 1 || user[0].Name != "login" || user[0].In != "path" || !user[0].Required {
+		t.Errorf("expected required login path parameter, got %+v", user)
+	}
+}
+
+func TestGenerateValueReceiver(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Id int
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	outputFile := filepath.Join(dir, "out.go")
+	err = generator.Generate(inputFile, outputFile)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	if !strings.Contains(string(code), "func (h *Api) handlerGet(") {
+		t.Errorf("expected handler for value receiver, got:\n%s", code)
+	}
+}
+
+func TestGenerateGenericReceiver(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api[T any] struct{}
+
+type GetParams struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api[T]) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || !strings.Contains(err.Error(), "unsupported receiver type *Api[T]") {
+		t.Errorf("expected unsupported receiver error, got %v", err)
+	}
+}
+
+func TestGeneratePointerInput(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.Generate("test/testdata/pointer/api.go", outputFile)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		"var params GetParams",
+		`params.Sku = queryParams.Get("sku")`,
+		"h.Get(r.Context(), &params)",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateQualifiedInput(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.Generate("test/testdata/qualified/api.go", outputFile)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		`ex "github.com/notrightending/gonerator/example"`,
+		"var params ex.ProfileParams",
+		`params.Login = strings.TrimSpace(queryParams.Get("login"))`,
+		"h.Profile(r.Context(), params)",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateUnsupportedSignature(t *testing.T) {
+	cases := []struct {
+		Signature string
+		Error     string
+	}{
+		{
+			Signature: "(ctx context.Context, in map[string]string) (*Item, error)",
+			Error:     "method Get: unsupported input type map[string]string",
+		},
+		{
+			Signature: "(ctx context.Context, in GetParams) (*Item, error)",
+			Error:     "input type GetParams not found for method Get",
+		},
+		{
+			Signature: "() (*Item, error)",
+			Error:     "method Get: unsupported signature: expected 1 or 2 parameters without context.Context, got 0 in func() (*Item, error)",
+		},
+		{
+			Signature: "(ctx context.Context) (*Item, error)",
+			Error:     "method Get: unsupported signature: expected 2 or 3 parameters, got 1 in func(ctx context.Context) (*Item, error)",
+		},
+		{
+			Signature: "(ctx context.Context, in Item, w http.ResponseWriter) (*Item, error)",
+			Error:     "method Get: unsupported signature: third parameter must be *http.Request in func(ctx context.Context, in Item, w http.ResponseWriter) (*Item, error)",
+		},
+		{
+			Signature: "(ctx echo.Context, in Item) (*Item, error)",
+			Error:     "method Get: unsupported signature: second parameter must be *http.Request in func(ctx echo.Context, in Item) (*Item, error)",
+		},
+		{
+			Signature: "(ctx, in Item) (*Item, error)",
+			Error:     "method Get: unsupported signature: second parameter must be *http.Request in func(ctx, in Item) (*Item, error)",
+		},
+		{
+			Signature: "(ctx context.Context, in Item) error",
+			Error:     "method Get: unsupported signature: expected 2 results, got 1 in func(ctx context.Context, in Item) error",
+		},
+		{
+			Signature: "(ctx context.Context, in Item) (chan Item, error)",
+			Error:     "method Get: unsupported result type chan Item",
+		},
+		{
+			Signature: "(ctx context.Context, in Item) (**Item, error)",
+			Error:     "method Get: unsupported result type **Item",
+		},
+		{
+			Signature: "(ctx context.Context, in Item) ([]http.Header, error)",
+			Error:     "method Get: unsupported result type []http.Header",
+		},
+		{
+			Signature: "(ctx context.Context, in Item) (*Item, string)",
+			Error:     "method Get: unsupported signature: second result must be error in func(ctx context.Context, in Item) (*Item, string)",
+		},
+	}
+
+	for _, item := range cases {
+		dir := t.TempDir()
+		inputFile := filepath.Join(dir, "api.go")
+		err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get`+item.Signature+` {
+	panic("not implemented")
+}
+`), 0644)
+		if err != nil {
+			t.Fatalf("cant write api.go: %v", err)
+		}
+
+		expected := inputFile + ":10: " + item.Error
+		err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+		if err == nil || err.Error() != expected {
+			t.Errorf("[%s] expected error %q, got %v", item.Signature, expected, err)
+		}
+	}
+
+	// context.Context is recognized by its import path, not its name
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import stdctx "context"
+
+type Api struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx stdctx.Context, in Item) (*Item, error) {
+	panic("not implemented")
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+	methods, err := generator.ParseMethods(inputFile)
+	if err != nil || len(methods) != 1 || !methods[0].WithContext {
+		t.Errorf("expected a method with a renamed context import, got %+v, %v", methods, err)
+	}
+}
+
+func TestGenerateInvalidAnnotations(t *testing.T) {
+	cases := []struct {
+		Config string
+		Tag    string
+		Error  string
+	}{
+		{
+			Config: `{"url": "/item/get", "auth": "yes"}`,
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: invalid apigen:api config: json: cannot unmarshal string into Go struct field ApiMethod.auth of type bool",
+		},
+		{
+			Config: `{"url": "/item/get"`,
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: invalid apigen:api config: unexpected end of JSON input",
+		},
+		{
+			Config: `{"url": "/item/get", "auth": true, "auth_scheme": "basic"}`,
+			Tag:    `apivalidator:"required"`,
+			Error:  `:13: method Get: unsupported auth_scheme "basic"`,
+		},
+		{
+			Config: `{"url": "/item/get", "timeout_ms": -1}`,
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: timeout_ms must be >= 0, got -1",
+		},
+		{
+			Config: `{"url": "/item/get", "max_body_bytes": -1}`,
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: max_body_bytes must be >= 0, got -1",
+		},
+		{
+			Config: "{\"url\": \"/item/get\",\n// apigen:api \"timeout_ms\": -1}",
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: timeout_ms must be >= 0, got -1",
+		},
+		{
+			Config: `yaml: url: /item/get, auth: maybe`,
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: invalid apigen:api config: json: cannot unmarshal string into Go struct field ApiMethod.auth of type bool",
+		},
+		{
+			Config: `yaml: url: /item/get, url: /item/other`,
+			Tag:    `apivalidator:"required"`,
+			Error:  `:13: method Get: invalid apigen:api config: yaml: line 2: mapping key "url" already defined at line 1`,
+		},
+		{
+			Config: `yaml: url /item/get`,
+			Tag:    `apivalidator:"required"`,
+			Error:  `:13: method Get: invalid apigen:api config: yaml: expected a mapping of keys to values, got "url /item/get"`,
+		},
+		{
+			Config: `{"url": "/item/get", "success_status": 302}`,
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: success_status must be a 2xx status, got 302",
+		},
+		{
+			Config: `{"url": "/item/{id}"}`,
+			Tag:    `apivalidator:"required"`,
+			Error:  ":13: method Get: path parameter id of /item/{id} does not match any field of GetParams",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"min=ten"`,
+			Error:  `:8: field GetParams.Sku: invalid apivalidator tag: min must be a number, got "ten"`,
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"enum=user|moderator|admin,default=guest"`,
+			Error:  `:8: field GetParams.Sku: invalid apivalidator tag: default "guest" must be one of [user, moderator, admin]`,
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"enum_ci=user|admin,default=root"`,
+			Error:  `:8: field GetParams.Sku: invalid apivalidator tag: default "root" must be one of [user, admin]`,
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"aliases=id||code"`,
+			Error:  `:8: field GetParams.Sku: invalid apivalidator tag: aliases must be parameter names separated by |, got "id||code"`,
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"paramname=id,aliases=code|sku|id"`,
+			Error:  ":13: method Get: alias id of GetParams.Sku collides with the parameter of GetParams.Sku",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"multiple_of=10"`,
+			Error:  ":8: field GetParams.Sku: invalid apivalidator tag: multiple_of can only be used on integer fields, got string",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"multiple_of=0"`,
+			Error:  `:8: field GetParams.Sku: invalid apivalidator tag: multiple_of must be a positive integer, got "0"`,
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"min=0.5"`,
+			Error:  ":8: field GetParams.Sku: invalid apivalidator tag: min must be an integer for string, got 0.5",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"max=10.5"`,
+			Error:  ":8: field GetParams.Sku: invalid apivalidator tag: max must be an integer for string, got 10.5",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"min=1,min_ex=0"`,
+			Error:  ":8: field GetParams.Sku: invalid apivalidator tag: min and min_ex cannot be used together",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"max_ex=10,max=9"`,
+			Error:  ":8: field GetParams.Sku: invalid apivalidator tag: max and max_ex cannot be used together",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"max_ex=many"`,
+			Error:  `:8: field GetParams.Sku: invalid apivalidator tag: max_ex must be a number, got "many"`,
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"regex=[a-z"`,
+			Error:  ":8: field GetParams.Sku: invalid apivalidator tag: error parsing regexp: missing closing ]: `[a-z`",
+		},
+		{
+			Config: `{"url": "/item/get"}`,
+			Tag:    `apivalidator:"required,regex=^(a|b$,msg=sku must be a or b"`,
+			Error:  ":8: field GetParams.Sku: invalid apivalidator tag: error parsing regexp: missing closing ): `^(a|b$`",
+		},
+	}
+
+	for _, item := range cases {
+		dir := t.TempDir()
+		inputFile := filepath.Join(dir, "api.go")
+		err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Sku string `+"`"+item.Tag+"`"+`
+}
+
+type Item struct{}
+
+// apigen:api `+item.Config+`
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+		if err != nil {
+			t.Fatalf("cant write api.go: %v", err)
+		}
+
+		expected := inputFile + item.Error
+		err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+		if err == nil || err.Error() != expected {
+			t.Errorf("[%s %s] expected error %q, got %v", item.Config, item.Tag, expected, err)
+		}
+	}
+}
+
+func TestGenerateEmbeddedCollision(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type Pagination struct {
+	Limit int
+}
+
+type ListParams struct {
+	Pagination
+	Limit int
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/list"}
+func (srv *Api) List(ctx context.Context, in ListParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	expected := inputFile + ":13: field ListParams.Limit: name collides with field Pagination.Limit"
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateInvalidDefault(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type ListParams struct {
+	Limit int `+"`"+`apivalidator:"default=abc"`+"`"+`
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/list"}
+func (srv *Api) List(ctx context.Context, in ListParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	expected := inputFile + `:8: field ListParams.Limit: invalid apivalidator tag: default must be int, got "abc"`
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateDuplicateRoute(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type MyApi struct{}
+
+type CreateParams struct {
+	Login string
+}
+
+type User struct{}
+
+// apigen:api {"url": "/user/create", "method": "POST"}
+func (srv *MyApi) Create(ctx context.Context, in CreateParams) (*User, error) {
+	return &User{}, nil
+}
+
+// apigen:api {"url": "/user/create"}
+func (srv *MyApi) CreateCopy(ctx context.Context, in CreateParams) (*User, error) {
+	return &User{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	expected := "duplicate route /user/create on MyApi: methods Create and CreateCopy must use different URLs"
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	// Different HTTP methods on one URL would generate duplicate cases
+	// and map keys, and register the URL twice
+	err = os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type ItemParams struct {
+	Name string
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item", "method": "GET"}
+func (srv *Api) Get(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+// apigen:api {"url": "/item", "method": "POST"}
+func (srv *Api) Create(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+	expected = "duplicate route /item on Api: methods Get and Create must use different URLs"
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateSharedURL(t *testing.T) {
+	// Methods of different receivers may share a URL
+	testGeneratedPackage(t, generator.Options{StrictMethods: true}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type AdminApi struct{}
+
+type ItemParams struct {
+	Name string
+}
+
+type Item struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// apigen:api {"url": "/item", "method": "GET"}
+func (srv *Api) Get(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{Name: "get " + in.Name}, nil
+}
+
+// apigen:api {"url": "/item", "method": "POST"}
+func (srv *AdminApi) Create(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{Name: "create " + in.Name}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSharedURL(t *testing.T) {
+	api := http.NewServeMux()
+	RegisterApiRoutes(api, &Api{})
+	admin := http.NewServeMux()
+	RegisterAdminApiRoutes(admin, &AdminApi{})
+
+	for _, c := range []struct {
+		Handler http.Handler
+		Method  string
+		Status  int
+		Body    string
+	}{
+		{api, http.MethodGet, http.StatusOK, "get box"},
+		{api, http.MethodPost, http.StatusMethodNotAllowed, "bad method"},
+		{admin, http.MethodPost, http.StatusOK, "create box"},
+		{admin, http.MethodGet, http.StatusMethodNotAllowed, "bad method"},
+	} {
+		r := httptest.NewRequest(c.Method, "/item?name=box", strings.NewReader("name=box"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		c.Handler.ServeHTTP(w, r)
+		if w.Code != c.Status || !strings.Contains(w.Body.String(), c.Body) {
+			t.Errorf("%s: expected %d %s, got %d %s", c.Method, c.Status, c.Body, w.Code, w.Body)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateSkipsUnchangedOutput(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "out.go")
+	inputFiles := []string{"example/api.go"}
+
+	written, err := generator.GeneratePackageChanged(inputFiles, outputFile, generator.Options{})
+	if err != nil || !written {
+		t.Fatalf("expected the output to be written, got %v, %v", written, err)
+	}
+	// Date the output back, as a fast rewrite may keep the same modification time
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(outputFile, modTime, modTime)
+	if err != nil {
+		t.Fatalf("cant set the modification time: %v", err)
+	}
+
+	written, err = generator.GeneratePackageChanged(inputFiles, outputFile, generator.Options{})
+	if err != nil || written {
+		t.Errorf("expected an up-to-date output not to be written, got %v, %v", written, err)
+	}
+	err = generator.Generate("example/api.go", outputFile)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	info, err := os.Stat(outputFile)
+	if err != nil {
+		t.Fatalf("cant stat the output: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("expected the modification time %v to be kept, got %v", modTime, info.ModTime())
+	}
+
+	written, err = generator.GeneratePackageChanged(inputFiles, outputFile, generator.Options{Healthz: true})
+	if err != nil || !written {
+		t.Errorf("expected a changed output to be written, got %v, %v", written, err)
+	}
+	info, err = os.Stat(outputFile)
+	if err != nil {
+		t.Fatalf("cant stat the output: %v", err)
+	}
+	if info.ModTime().Equal(modTime) {
+		t.Errorf("expected the modification time to change")
+	}
+}
+
+func TestCheckPackage(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "out.go")
+	inputFiles := []string{"example/api.go"}
+
+	diff, err := generator.CheckPackage(inputFiles, outputFile, generator.Options{})
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if !strings.Contains(diff, "+package example") {
+		t.Errorf("expected diff adding the missing file, got:\n%s", diff)
+	}
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Errorf("expected check not to write %s, got %v", outputFile, err)
+	}
+
+	err = generator.GeneratePackage(inputFiles, outputFile, generator.Options{})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	diff, err = generator.CheckPackage(inputFiles, outputFile, generator.Options{})
+	if err != nil || diff != "" {
+		t.Errorf("expected no diff for an up-to-date file, got %v:\n%s", err, diff)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read generated file: %v", err)
+	}
+	stale := strings.Replace(string(code), "http.StatusInternalServerError", "http.StatusTeapot", 1)
+	err = os.WriteFile(outputFile, []byte(stale), 0644)
+	if err != nil {
+		t.Fatalf("cant write stale file: %v", err)
+	}
+	diff, err = generator.CheckPackage(inputFiles, outputFile, generator.Options{})
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	for _, expected := range []string{"--- " + outputFile + "\n", "\n@@ -", "\n-\treturn http.StatusTeapot\n+\treturn http.StatusInternalServerError\n"} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("expected diff to contain %q, got:\n%s", expected, diff)
+		}
+	}
+}
+
+func TestCheckFlag(t *testing.T) {
+	args := []string{"-check", "-client", "example/client/generated_client.go", "-mocks", "-cors", "*", "-introspect", "-healthz", "-xml", "-gzip", "example/api.go", "example/generated_api.go"}
+	out, err := exec.Command("./generator", args...).CombinedOutput()
+	if err != nil {
+		t.Errorf("expected generated files to be up to date, got %v:\n%s", err, out)
+	}
+
+	args = append([]string{"-strict-methods"}, args...)
+	out, err = exec.Command("./generator", args...).CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1 for a stale file, got %v", err)
+	}
+	if !strings.Contains(string(out), "+++ example/generated_api.go (generated)") {
+		t.Errorf("expected a diff of example/generated_api.go, got:\n%s", out)
+	}
+}
+
+// lintSource has one method of every kind of lint warning and a clean one.
+const lintSource = `package example
+
+import "context"
+
+type Api struct{}
+
+type Item struct{}
+
+type PlainParams struct {
+	Name string
+	Page int ` + "`apivalidator:\"default=1\"`" + `
+}
+
+// apigen:api {"url": "/plain"}
+func (srv *Api) Plain(ctx context.Context, in PlainParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+type SecretParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+
+// apigen:api {"url": "/secret", "auth": true}
+func (srv *Api) Secret(ctx context.Context, in SecretParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+type ListParams struct {
+	Sort  string ` + "`apivalidator:\"enum_ci=name|price|Name\"`" + `
+	Limit int    ` + "`apivalidator:\"required,default=10\"`" + `
+}
+
+// apigen:api {"url": "/list"}
+func (srv *Api) List(ctx context.Context, in ListParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+type CleanParams struct{}
+
+// apigen:api {"url": "/clean", "auth": true, "auth_env_key": "CLEAN_KEY"}
+func (srv *Api) Clean(ctx context.Context, in CleanParams) (*Item, error) {
+	return &Item{}, nil
+}
+`
+
+func TestLintPackage(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "api.go")
+	err := os.WriteFile(inputFile, []byte(lintSource), 0644)
+	if err != nil {
+		t.Fatalf("cant write input: %v", err)
+	}
+
+	warnings, err := generator.LintPackage([]string{inputFile}, generator.Options{})
+	if err != nil {
+		t.Fatalf("lint failed: %v", err)
+	}
+	expected := []string{
+		inputFile + ":14: method Plain: input type PlainParams has no validated fields",
+		inputFile + ":23: method Secret: auth is set without auth_env_key, the key is read from API_AUTH_KEY",
+		inputFile + `:33: method List: field Sort: enum value "Name" is listed more than once`,
+		inputFile + `:33: method List: field Limit: default "10" is never used as the field is required`,
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("expected warnings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(warnings, "\n"))
+	}
+
+	warnings, err = generator.LintPackage([]string{"example/api.go"}, generator.Options{})
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("expected no warnings for the example, got %v: %v", err, warnings)
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	methods, err := generator.ParseMethods("example/api.go")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(methods) != 12 {
+		t.Fatalf("expected 12 methods, got %d", len(methods))
+	}
+
+	profile := methods[0]
+	if profile.Name != "Profile" || profile.ReceiverType != "MyApi" || profile.InputType != "ProfileParams" ||
+		profile.OutputResult() != "*User" || !profile.WithContext || profile.WithRequest || profile.File != "example/api.go" {
+		t.Errorf("unexpected Profile method: %+v", profile)
+	}
+	if profile.ApiMethod.Url != "/user/profile" || profile.ApiMethod.Method != "GET,POST" || profile.ApiMethod.Auth {
+		t.Errorf("unexpected Profile config: %+v", profile.ApiMethod)
+	}
+	if len(profile.StructFields) != 1 || profile.StructFields[0].Name != "Login" ||
+		!profile.StructFields[0].Tag.Required || !profile.StructFields[0].Tag.Trim {
+		t.Errorf("unexpected Profile fields: %+v", profile.StructFields)
+	}
+
+	create := methods[1]
+	if create.Name != "Create" || !create.ApiMethod.Auth || create.ApiMethod.AuthEnvKey != "MY_API_KEY" || create.ApiMethod.Method != "POST" {
+		t.Errorf("unexpected Create method: %+v", create)
+	}
+	name := create.Field("full_name")
+	if name == nil || name.Name != "Name" || !reflect.DeepEqual(name.Tag.Aliases, []string{"name", "fullname"}) {
+		t.Errorf("unexpected full_name field: %+v", name)
+	}
+	login := create.Field("login")
+	if login == nil || login.Tag.Min == nil || *login.Tag.Min != 10 || login.Tag.Custom != "ValidateLogin" || login.Tag.Code != "INVALID_LOGIN" {
+		t.Errorf("unexpected login field: %+v", login)
+	}
+
+	if _, err := generator.ParseMethods("example/missing.go"); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestLintFlag(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "api.go")
+	err := os.WriteFile(inputFile, []byte(lintSource), 0644)
+	if err != nil {
+		t.Fatalf("cant write input: %v", err)
+	}
+
+	out, err := exec.Command("./generator", "-lint", inputFile).CombinedOutput()
+	if err != nil {
+		t.Errorf("expected -lint to exit with 0, got %v:\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "method Plain: input type PlainParams has no validated fields") {
+		t.Errorf("expected warnings, got:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(inputFile), "api_gen.go")); !os.IsNotExist(err) {
+		t.Errorf("expected -lint not to generate handlers, got %v", err)
+	}
+
+	_, err = exec.Command("./generator", "-lint-strict", inputFile).CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1 for -lint-strict with warnings, got %v", err)
+	}
+
+	out, err = exec.Command("./generator", "-lint-strict", "example/api.go").CombinedOutput()
+	if err != nil {
+		t.Errorf("expected -lint-strict to exit with 0 without warnings, got %v:\n%s", err, out)
+	}
+}
+
+func TestDumpFlag(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	source, err := os.ReadFile("example/api.go")
+	if err != nil {
+		t.Fatalf("cant read example: %v", err)
+	}
+	err = os.WriteFile(inputFile, source, 0644)
+	if err != nil {
+		t.Fatalf("cant write input: %v", err)
+	}
+
+	out, err := exec.Command("./generator", "-dump", inputFile).Output()
+	if err != nil {
+		t.Fatalf("dump failed: %v", err)
+	}
+	var methods []generator.Method
+	err = json.Unmarshal(out, &methods)
+	if err != nil {
+		t.Fatalf("cant unpack dump %s: %v", out, err)
+	}
+	if len(methods) == 0 {
+		t.Fatalf("expected methods in the dump, got %s", out)
+	}
+
+	create := methods[1]
+	if create.ReceiverType != "MyApi" || create.Name != "Create" || create.InputType != "CreateParams" {
+		t.Errorf("expected MyApi.Create(CreateParams), got %s.%s(%s)", create.ReceiverType, create.Name, create.InputType)
+	}
+	if create.ApiMethod.Url != "/user/create" || create.ApiMethod.Method != "POST" || create.ApiMethod.AuthEnvKey != "MY_API_KEY" {
+		t.Errorf("unexpected ApiMethod %+v", create.ApiMethod)
+	}
+	if len(create.StructFields) == 0 || create.StructFields[0].Name != "Login" || !create.StructFields[0].Tag.Required {
+		t.Errorf("expected a required Login field first, got %+v", create.StructFields)
+	}
+	if !strings.Contains(string(out), `"url": "/user/create"`) {
+		t.Errorf("expected the JSON names of ApiMethod in the dump, got:\n%s", out)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected -dump not to write files, got %v %v", entries, err)
+	}
+}
+
+func TestGenerateImportsSubset(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type ApiError struct {
+	HTTPStatus int
+	Err        error
+}
+
+func (ae ApiError) Error() string {
+	return ae.Err.Error()
+}
+
+type GetParams struct {
+	Sku string `+"`"+`apivalidator:"required"`+"`"+`
+}
+
+type Item struct {
+	Sku string `+"`"+`json:"sku"`+"`"+`
+}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{Sku: in.Sku}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	outputFile := filepath.Join(dir, "api_gen.go")
+	err = generator.GenerateWithOptions(inputFile, outputFile, generator.Options{NoRecover: true})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read generated file: %v", err)
+	}
+	for _, unused := range []string{`"crypto/subtle"`, `"log"`, `"net/mail"`, `"regexp"`, `"time"`} {
+		if strings.Contains(string(code), unused) {
+			t.Errorf("expected generated code not to import %s", unused)
+		}
+	}
+
+	out, err := exec.Command("go", "vet", inputFile, outputFile).CombinedOutput()
+	if err != nil {
+		t.Errorf("generated code does not compile: %v\n%s", err, out)
+	}
+}
+
+func TestGeneratedHeader(t *testing.T) {
+	header := regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+	for file, packageName := range map[string]string{
+		"example/generated_api.go":           "example",
+		"example/client/generated_client.go": "client",
+		"example/generated_api_mock.go":      "example",
+	} {
+		code, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("cant read %s: %v", file, err)
+		}
+		first, rest, _ := strings.Cut(string(code), "\n")
+		if !header.MatchString(first) {
+			t.Errorf("%s: expected a generated code header, got %q", file, first)
+		}
+		if expected := "// Code generated by gonerator from api.go; DO NOT EDIT."; first != expected {
+			t.Errorf("%s: expected header %q, got %q", file, expected, first)
+		}
+		if !strings.HasPrefix(rest, "\npackage "+packageName+"\n") {
+			t.Errorf("%s: expected a blank line between the header and the package clause", file)
+		}
+	}
+}
+
+func TestGenerateBuildConstraint(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"api.go": `//go:build linux || darwin
+
+package example
+
+import "context"
+
+type Api struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in Item) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"other.go": `// Other API methods.
+
+//go:build !race
+
+package example
+
+import "context"
+
+type OtherApi struct{}
+
+// apigen:api {"url": "/item/other"}
+func (srv *OtherApi) Get(ctx context.Context, in Item) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", name, err)
+		}
+	}
+
+	cases := []struct {
+		Inputs []string
+		Header string
+	}{
+		{
+			Inputs: []string{"api.go"},
+			Header: "// Code generated by gonerator from api.go; DO NOT EDIT.\n\n//go:build linux || darwin\n\npackage example\n",
+		},
+		{
+			Inputs: []string{"api.go", "other.go"},
+			Header: "// Code generated by gonerator from api.go, other.go; DO NOT EDIT.\n\n//go:build (linux || darwin) && !race\n\npackage example\n",
+		},
+	}
+	for _, item := range cases {
+		var inputFiles []string
+		for _, input := range item.Inputs {
+			inputFiles = append(inputFiles, filepath.Join(dir, input))
+		}
+		outputFile := filepath.Join(dir, "api_gen.go")
+		err := generator.GeneratePackage(inputFiles, outputFile, generator.Options{})
+		if err != nil {
+			t.Fatalf("%v: generate failed: %v", item.Inputs, err)
+		}
+
+		code, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("cant read output: %v", err)
+		}
+		if !strings.HasPrefix(string(code), item.Header) {
+			t.Errorf("%v: expected the output to start with %q, got:\n%s", item.Inputs, item.Header, code)
+		}
+	}
+}
+
+func TestGenerateCRLF(t *testing.T) {
+	src, err := os.ReadFile("example/api.go")
+	if err != nil {
+		t.Fatalf("cant read example/api.go: %v", err)
+	}
+	crlf := append([]byte("\xef\xbb\xbf"), bytes.ReplaceAll(src, []byte("\n"), []byte("\r\n"))...)
+
+	outputs := make(map[string][]byte)
+	for name, content := range map[string][]byte{"lf": src, "crlf": crlf} {
+		dir := filepath.Join(t.TempDir(), name)
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			t.Fatalf("cant create %s: %v", dir, err)
+		}
+		inputFile := filepath.Join(dir, "api.go")
+		err = os.WriteFile(inputFile, content, 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", inputFile, err)
+		}
+
+		outputFile := filepath.Join(dir, "api_gen.go")
+		err = generator.GenerateWithOptions(inputFile, outputFile, generator.Options{Introspect: true})
+		if err != nil {
+			t.Fatalf("%s: generate failed: %v", name, err)
+		}
+		outputs[name], err = os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("cant read %s: %v", outputFile, err)
+		}
+	}
+
+	if !bytes.Equal(outputs["crlf"], outputs["lf"]) {
+		t.Errorf("expected the same output for CRLF input, got:\n%s", outputs["crlf"])
+	}
+}
+
+func TestGenerateCustomTemplate(t *testing.T) {
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "routes.tmpl")
+	err := os.WriteFile(templateFile, []byte(`package {{.PackageName}}
+
+// Routes lists the API methods and their URLs.
+var Routes = []string{
+{{- range $receiverType, $methods := .Methods}}
+{{- range $methods}}
+	"{{$receiverType}}.{{.Name}} {{join (httpMethods .ApiMethod.Method) ","}} {{.ApiMethod.Url}}",
+{{- end}}
+{{- end}}
+}
+
+// Naming shows the case conversions of the naming helpers.
+const Naming = "{{camel "HTTPStatus"}} {{pascal "user-id"}} {{camel "full name"}} {{pascal "ID"}}"
+
+// Params lists the request parameters of the API methods.
+var Params = []string{
+{{- range $receiverType, $methods := .Methods}}
+{{- range $method := $methods}}
+{{- range .StructFields}}
+	"{{$receiverType}}.{{$method.Name}}.{{.Name}} {{jsonTag .}} {{camel (jsonTag .)}} {{pascal (jsonTag .)}} {{lower .Name}}",
+{{- end}}
+{{- end}}
+{{- end}}
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write template: %v", err)
+	}
+
+	outputFile := filepath.Join(dir, "routes.go")
+	out, err := exec.Command("./generator", "-template", templateFile, "example/api.go", outputFile).CombinedOutput()
+	if err != nil {
+		t.Fatalf("generator failed: %v\n%s", err, out)
+	}
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		"// Code generated by gonerator from api.go; DO NOT EDIT.\n\npackage example\n",
+		"\t\"MyApi.Profile GET,POST /user/profile\",\n",
+		"\t\"ProductApi.List GET /product/list\",\n",
+		"const Naming = \"httpStatus UserId fullName Id\"\n",
+		"\t\"MyApi.Create.Login login login Login login\",\n",
+		"\t\"MyApi.Create.Name full_name fullName FullName name\",\n",
+		"\t\"OtherApi.Create.Name account_name accountName AccountName name\",\n",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+
+	watchDir := filepath.Join(dir, "watch")
+	err = os.MkdirAll(watchDir, 0755)
+	if err != nil {
+		t.Fatalf("cant create watch dir: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(watchDir, "api.go"), []byte(apiSource("watch", "Watch", "/watch")), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+	_, err = generator.NewFileCache().GenerateDir(watchDir, generator.DefaultOutPattern, generator.Options{TemplateFile: templateFile})
+	if err != nil {
+		t.Fatalf("FileCache.GenerateDir failed: %v", err)
+	}
+	code, err = os.ReadFile(filepath.Join(watchDir, "api_gen.go"))
+	if err != nil {
+		t.Fatalf("cant read watch output: %v", err)
+	}
+	if want := "\t\"Watch.Get GET,POST /watch\",\n"; !strings.Contains(string(code), want) {
+		t.Errorf("expected watch output to contain %q, got:\n%s", want, code)
+	}
+
+	err = os.WriteFile(templateFile, []byte("package {{.PackageName"), 0644)
+	if err != nil {
+		t.Fatalf("cant write template: %v", err)
+	}
+	err = generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{TemplateFile: templateFile})
+	if err == nil || !strings.Contains(err.Error(), "routes.tmpl") {
+		t.Errorf("expected a template parse error, got %v", err)
+	}
+	err = generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{TemplateFile: filepath.Join(dir, "missing.tmpl")})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing template error, got %v", err)
+	}
+}
+
+func TestGenerateCORSMethodsAndHeaders(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{
+		CORSOrigin:  "https://example.com",
+		CORSMethods: []string{"GET", "POST", "DELETE"},
+		CORSHeaders: []string{"X-Request-ID", "X-Trace"},
+	}, map[string]string{
+		"api.go": routerFixture,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	for _, path := range []string{"/items/7", "/item/create"} {
+		w := httptest.NewRecorder()
+		(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodOptions, path, nil))
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s: expected status 204, got %d", path, w.Code)
+		}
+		for header, want := range map[string]string{
+			"Access-Control-Allow-Origin":  "https://example.com",
+			"Access-Control-Allow-Methods": "GET, POST, DELETE",
+			"Access-Control-Allow-Headers": "Content-Type, X-Request-ID, X-Trace",
+		} {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("%s: expected %s %q, got %q", path, header, want, got)
+			}
+		}
+	}
+}
+`,
+	})
+
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{CORSOrigin: "*", CORSHeaders: []string{"X-Bad\"Header"}})
+	if err == nil || err.Error() != `invalid CORS header "X-Bad\"Header"` {
+		t.Errorf("expected an invalid header error, got %v", err)
+	}
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	opts := generator.Options{
+		ClientFile: filepath.Join(dir, "client", "client.go"),
+		MocksFile:  filepath.Join(dir, "mock.go"),
+		CORSOrigin: "*",
+		Introspect: true,
+		Healthz:    true,
+		Router:     generator.RouterChi,
+	}
+
+	var previous map[string][]byte
+	for i := 0; i < 5; i++ {
+		err := generator.GenerateWithOptions("example/api.go", filepath.Join(dir, "api.go"), opts)
+		if err != nil {
+			t.Fatalf("generate failed: %v", err)
+		}
+
+		outputs := make(map[string][]byte)
+		for _, name := range []string{"api.go", filepath.Join("client", "client.go"), "mock.go"} {
+			outputs[name], err = os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("cant read %s: %v", name, err)
+			}
+			if previous != nil && !bytes.Equal(outputs[name], previous[name]) {
+				t.Errorf("run %d: %s differs from the previous run", i, name)
+			}
+		}
+		previous = outputs
+	}
+}
+
+func TestGenerateNestedOutput(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "gen", "api", "handlers.go")
+	opts := generator.Options{
+		OpenAPIFile: filepath.Join(dir, "docs", "openapi.json"),
+		ClientFile:  filepath.Join(dir, "gen", "client", "client.go"),
+	}
+
+	err := generator.GenerateWithOptions("example/api.go", outputFile, opts)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	for _, file := range []string{outputFile, opts.OpenAPIFile, opts.ClientFile} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("expected %s to be written, got %v", file, err)
+		}
+	}
+
+	// A file in place of the output directory cannot be replaced
+	blocker := filepath.Join(dir, "blocker")
+	err = os.WriteFile(blocker, nil, 0644)
+	if err != nil {
+		t.Fatalf("cant write blocker: %v", err)
+	}
+	err = generator.Generate("example/api.go", filepath.Join(blocker, "handlers.go"))
+	if err == nil || !strings.HasPrefix(err.Error(), "cannot create output directory: ") {
+		t.Errorf("expected an output directory error, got %v", err)
+	}
+}
+
+func TestGenerateInvalidPackageName(t *testing.T) {
+	for _, name := range []string{"my-api", "1api", "func"} {
+		outputFile := filepath.Join(t.TempDir(), "out.go")
+		err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{PackageName: name})
+		expected := fmt.Sprintf("invalid package name %q", name)
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected error %q, got %v", expected, err)
+		}
+		if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be written, got %v", outputFile, err)
+		}
+	}
+}
+
+func TestGenerateSplit(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile("example/api.go")
+	if err != nil {
+		t.Fatalf("cant read api.go: %v", err)
+	}
+	inputFile := filepath.Join(dir, "api.go")
+	err = os.WriteFile(inputFile, src, 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	err = generator.GenerateSplit([]string{inputFile}, dir, generator.Options{})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	files := map[string]string{"MyApi": "myapi_gen.go", "OtherApi": "otherapi_gen.go", "ProductApi": "productapi_gen.go"}
+	for receiverType, name := range files {
+		path := generator.SplitPath(dir, receiverType)
+		if path != filepath.Join(dir, name) {
+			t.Errorf("expected %s to be written to %s, got %s", receiverType, name, path)
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("cant read %s: %v", name, err)
+		}
+		for other := range files {
+			serves := strings.Contains(string(code), "func (h *"+other+") ServeHTTP(")
+			if serves != (other == receiverType) {
+				t.Errorf("%s: expected ServeHTTP of %s only, got ServeHTTP of %s: %v", name, receiverType, other, serves)
+			}
+		}
+		shared := strings.Contains(string(code), "var ErrorStatuses")
+		if shared != (receiverType == "MyApi") {
+			t.Errorf("%s: expected shared declarations in myapi_gen.go only, got %v", name, shared)
+		}
+	}
+
+	code, err := os.ReadFile(filepath.Join(dir, "otherapi_gen.go"))
+	if err != nil {
+		t.Fatalf("cant read otherapi_gen.go: %v", err)
+	}
+	if strings.Contains(string(code), `"net/mail"`) {
+		t.Errorf("expected otherapi_gen.go to import only what its handlers use")
+	}
+
+	args := []string{"vet", inputFile}
+	for _, name := range files {
+		args = append(args, filepath.Join(dir, name))
+	}
+	out, err := exec.Command("go", args...).CombinedOutput()
+	if err != nil {
+		t.Errorf("generated code does not compile: %v\n%s", err, out)
+	}
+
+	// The mocks are named after the source file, so the outputs of several
+	// sources can share a directory
+	mocksDir := t.TempDir()
+	out, err = exec.Command("./generator", "-split", "-mocks", "-input", inputFile, "-output", mocksDir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("generator -split -mocks failed: %v\n%s", err, out)
+	}
+	mocksFile := generator.SplitMocksPath(mocksDir, inputFile)
+	if mocksFile != filepath.Join(mocksDir, "api_mock_gen.go") {
+		t.Errorf("expected the mocks to be written to api_mock_gen.go, got %s", mocksFile)
+	}
+	if _, err := os.Stat(mocksFile); err != nil {
+		t.Errorf("expected %s to be written: %v", mocksFile, err)
+	}
+}
+
+func TestGenerateHideInternalErrors(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{HideInternalErrors: true}, map[string]string{
+		"api.go": `package generated
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type Api struct{}
+
+type GetParams struct {
+	Kind string
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	switch in.Kind {
+	case "plain":
+		return nil, errors.New("connection refused")
+	case "not_found":
+		return nil, ApiError{http.StatusNotFound, errors.New("item not found")}
+	case "unavailable":
+		return nil, ApiError{http.StatusServiceUnavailable, errors.New("replica down")}
+	}
+	return &Item{}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		Kind   string
+		Status int
+		Body   string
+	}{
+		{"plain", http.StatusInternalServerError, "{\"error\": \"internal server error\"}"},
+		{"unavailable", http.StatusServiceUnavailable, "{\"error\": \"internal server error\"}"},
+		{"not_found", http.StatusNotFound, "{\"error\": \"item not found\"}"},
+	}
+	for _, item := range cases {
+		w := httptest.NewRecorder()
+		(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?kind="+item.Kind, nil))
+		if w.Code != item.Status {
+			t.Errorf("%s: expected status %d, got %d", item.Kind, item.Status, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != item.Body {
+			t.Errorf("%s: expected body %s, got %s", item.Kind, item.Body, body)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateErrorStatuses(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+)
+
+func init() {
+	ErrorStatuses = append(ErrorStatuses,
+		ErrorStatus{Err: ErrNotFound, Status: http.StatusNotFound},
+		ErrorStatus{Err: ErrConflict, Status: http.StatusConflict},
+	)
+}
+
+type Api struct{}
+
+type GetParams struct {
+	Kind string
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	switch in.Kind {
+	case "conflict":
+		return nil, ErrConflict
+	case "both":
+		return nil, errors.Join(ErrConflict, ErrNotFound)
+	case "deadline":
+		return nil, context.DeadlineExceeded
+	}
+	return &Item{}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorStatuses(t *testing.T) {
+	cases := []struct {
+		Kind   string
+		Status int
+	}{
+		{"conflict", http.StatusConflict},
+		{"deadline", http.StatusGatewayTimeout},
+		// The first matching entry wins, whatever the order of the wrapped errors
+		{"both", http.StatusNotFound},
+	}
+	for _, item := range cases {
+		for i := 0; i < 20; i++ {
+			w := httptest.NewRecorder()
+			(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?kind="+item.Kind, nil))
+			if w.Code != item.Status {
+				t.Fatalf("%s: expected status %d, got %d", item.Kind, item.Status, w.Code)
+			}
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateEnvelope(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Options  generator.Options
+		Body     string
+		ErrorKey string
+	}{
+		{generator.EnvelopeData, generator.Options{Envelope: generator.EnvelopeData}, "{\"data\":{\"name\":\"box\"}}", "error"},
+		{generator.EnvelopeBare, generator.Options{Envelope: generator.EnvelopeBare}, "{\"name\":\"box\"}", "error"},
+		{"keys", generator.Options{ErrorKey: "message", ResponseKey: "result"}, "{\"message\":\"\",\"result\":{\"name\":\"box\"}}", "message"},
+		{"data keys", generator.Options{Envelope: generator.EnvelopeData, ErrorKey: "msg", ResponseKey: "payload"}, "{\"payload\":{\"name\":\"box\"}}", "msg"},
+	}
+	for _, item := range cases {
+		t.Run(item.Name, func(t *testing.T) {
+			testGeneratedPackage(t, item.Options, map[string]string{
+				"api.go": `package generated
+
+import (
+	"context"
+	"errors"
+)
+
+type Api struct{}
+
+type GetParams struct {
+	Name  string
+	Count int
+}
+
+type Item struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	if in.Name == "" {
+		return nil, errors.New("no name")
+	}
+	return &Item{Name: in.Name}, nil
+}
+`,
+				"api_test.go": `package generated_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "generated"
+	. "generated/client"
+)
+
+func TestEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?name=box", nil))
+	if body := strings.Join(strings.Fields(w.Body.String()), ""); body != ` + "`" + item.Body + "`" + ` {
+		t.Errorf("expected body %s, got %s", ` + "`" + item.Body + "`" + `, body)
+	}
+
+	w = httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get", nil))
+	if body := strings.Join(strings.Fields(w.Body.String()), ""); body != ` + "`{\"" + item.ErrorKey + "\":\"noname\"}`" + ` {
+		t.Errorf("expected an error body, got %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?name=box&count=x", nil))
+	if body := strings.Join(strings.Fields(w.Body.String()), ""); body != ` + "`{\"" + item.ErrorKey + "\":\"countmustbeint\",\"code\":\"VALIDATION_ERROR\"}`" + ` {
+		t.Errorf("expected a validation error body, got %s", body)
+	}
+
+	srv := httptest.NewServer(&Api{})
+	defer srv.Close()
+	client := NewApiClient(srv.URL, "")
+	res, err := client.Get(context.Background(), GetParams{Name: "box"})
+	if err != nil || res.Name != "box" {
+		t.Errorf("expected box, got %v, %v", res, err)
+	}
+	_, err = client.Get(context.Background(), GetParams{})
+	if err == nil || !strings.Contains(err.Error(), "no name") {
+		t.Errorf("expected the error of the method, got %v", err)
+	}
+}
+`,
+			})
+		})
+	}
+}
+
+func TestGenerateEnvelopeCollectedErrors(t *testing.T) {
+	dir := t.TempDir()
+	opts := generator.Options{CollectErrors: true, ErrorKey: "message", OpenAPIFile: filepath.Join(dir, "openapi.json")}
+	testGeneratedPackage(t, opts, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Name  string ` + "`apivalidator:\"required\"`" + `
+	Count int
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"api_test.go": `package generated_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "generated"
+	. "generated/client"
+)
+
+func TestCollectedErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?count=x", nil))
+	if want := ` + "`{\"messages\":[\"name must be not empty\",\"count must be int\"]}`" + `; w.Code != http.StatusBadRequest || w.Body.String() != want+"\n" {
+		t.Errorf("expected %s, got %d %s", want, w.Code, w.Body)
+	}
+
+	srv := httptest.NewServer(&Api{})
+	defer srv.Close()
+	_, err := NewApiClient(srv.URL, "").Get(context.Background(), GetParams{})
+	if err == nil || !strings.Contains(err.Error(), "name must be not empty") {
+		t.Errorf("expected the collected errors, got %v", err)
+	}
+}
+`,
+	})
+
+	spec, err := os.ReadFile(opts.OpenAPIFile)
+	if err != nil {
+		t.Fatalf("cant read openapi spec: %v", err)
+	}
+	if !strings.Contains(string(spec), `"messages": {`) {
+		t.Errorf("expected the error schema to describe messages, got:\n%s", spec)
+	}
+}
+
+func TestGenerateInvalidEnvelopeKeys(t *testing.T) {
+	cases := []struct {
+		Options generator.Options
+		Error   string
+	}{
+		{generator.Options{Envelope: generator.EnvelopeBare, ResponseKey: "result"}, "response key cannot be used with the bare envelope"},
+		{generator.Options{ErrorKey: "my error"}, `invalid envelope key "my error"`},
+		{generator.Options{ResponseKey: "error"}, `error and response keys must differ, got "error" for both`},
+		{generator.Options{ErrorKey: "item", ResponseKey: "items"}, `collected errors and response keys must differ, got "items" for both`},
+		{generator.Options{Envelope: "xml"}, `unsupported envelope "xml"`},
+	}
+	for _, item := range cases {
+		err := generator.GenerateWithOptions("example/api.go", filepath.Join(t.TempDir(), "api.go"), item.Options)
+		if err == nil || err.Error() != item.Error {
+			t.Errorf("%+v: expected error %q, got %v", item.Options, item.Error, err)
+		}
+	}
+}
+
+func TestGenerateEncodeError(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import (
+	"context"
+	"math"
+)
+
+type Api struct{}
+
+type GetParams struct {
+	Infinite bool
+}
+
+type Item struct {
+	Value float64 ` + "`json:\"value\"`" + `
+}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	if in.Infinite {
+		return &Item{Value: math.Inf(1)}, nil
+	}
+	return &Item{Value: 1}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEncodeError(t *testing.T) {
+	cases := []struct {
+		Query  string
+		Status int
+		Body   string
+	}{
+		{"infinite=true", http.StatusInternalServerError, "{\"error\": \"cannot encode response\"}"},
+		{"infinite=false", http.StatusOK, "{\"error\":\"\",\"response\":{\"value\":1}}"},
+		{"infinite=true", http.StatusInternalServerError, "{\"error\": \"cannot encode response\"}"},
+		{"infinite=false", http.StatusOK, "{\"error\":\"\",\"response\":{\"value\":1}}"},
+	}
+	for _, item := range cases {
+		w := httptest.NewRecorder()
+		(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?"+item.Query, nil))
+		if w.Code != item.Status {
+			t.Errorf("%s: expected status %d, got %d", item.Query, item.Status, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != item.Body {
+			t.Errorf("%s: expected body %s, got %s", item.Query, item.Body, body)
+		}
+	}
+}
+`,
+	})
+}
+
+// testGeneratedPackage generates handlers and a client for api.go of a
+// package made of files in a temporary module and runs the package tests.
+// A MocksFile of opts is relative to the package directory.
+// sharedFixtures are written to every package of testGeneratedPackage:
+// the ApiError type the generated code expects, and serve, which sends a
+// form request with name=box and an X-Auth header to Api.
+var sharedFixtures = map[string]string{
+	"api_error.go": `package generated
+
+type ApiError struct {
+	HTTPStatus int
+	Err        error
+}
+
+func (ae ApiError) Error() string {
+	return ae.Err.Error()
+}
+`,
+	"serve_test.go": `package generated
+
+import (
+	"net/http/httptest"
+	"strings"
+)
+
+func serve(method, path, auth string) int {
+	r := httptest.NewRequest(method, path, strings.NewReader("name=box"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Auth", auth)
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, r)
+	return w.Code
+}
+`,
+}
+
+// generatedModules returns the modules imported by the code generated with
+// opts and by the tests of its features.
+func generatedModules(opts generator.Options) []string {
+	var modules []string
+	switch opts.Router {
+	case generator.RouterChi:
+		modules = append(modules, "github.com/go-chi/chi/v5")
+	case generator.RouterGin:
+		modules = append(modules, "github.com/gin-gonic/gin")
+	}
+	if opts.Metrics {
+		modules = append(modules, "github.com/prometheus/client_golang")
+	}
+	if opts.Otel {
+		modules = append(modules, "go.opentelemetry.io/otel", "go.opentelemetry.io/otel/trace", "go.opentelemetry.io/otel/sdk")
+	}
+	return modules
+}
+
+func testGeneratedPackage(t *testing.T, opts generator.Options, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module generated\n\ngo 1.22\n"
+	for name, content := range sharedFixtures {
+		files[name] = content
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("cant write %s: %v", name, err)
+		}
+	}
+
+	opts.ClientFile = filepath.Join(dir, "client", "client_gen.go")
+	if opts.MocksFile != "" {
+		opts.MocksFile = filepath.Join(dir, opts.MocksFile)
+	}
+	err := generator.GenerateWithOptions(filepath.Join(dir, "api.go"), filepath.Join(dir, "api_gen.go"), opts)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	modules := generatedModules(opts)
+	if len(modules) > 0 {
+		cmd := exec.Command("go", append([]string{"get"}, modules...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go get failed: %v\n%s", err, out)
+		}
+		cmd = exec.Command("go", "mod", "tidy")
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go mod tidy failed: %v\n%s", err, out)
+		}
+	}
+
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Errorf("generated code failed: %v\n%s", err, out)
+	}
+	if !strings.HasPrefix(string(out), "ok") {
+		t.Errorf("expected the generated code to be tested, got:\n%s", out)
+	}
+}
+
+func TestGenerateMultiLineConfig(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type ItemParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+
+type Item struct{}
+
+// Create creates an item.
+// apigen:api {"url": "/item/create",
+// apigen:api  "method": "POST",
+// apigen:api  "success_status": 201}
+//
+// The config ends at the first line without apigen:api.
+func (srv *Api) Create(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+/* apigen:api {
+	"url": "/item/delete",
+	"method": "POST",
+	"auth": true,
+	"auth_env_key": "ITEM_KEY"
+} */
+func (srv *Api) Delete(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMultiLineConfig(t *testing.T) {
+	t.Setenv("ITEM_KEY", "secret")
+	for _, c := range []struct {
+		method, path, auth string
+		status             int
+	}{
+		{http.MethodPost, "/item/create", "", http.StatusCreated},
+		{http.MethodGet, "/item/create?name=box", "", http.StatusNotAcceptable},
+		{http.MethodPost, "/item/delete", "", http.StatusForbidden},
+		{http.MethodPost, "/item/delete", "secret", http.StatusOK},
+	} {
+		if status := serve(c.method, c.path, c.auth); status != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.status, status)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateYAMLConfig(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type ItemParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+
+type Item struct{}
+
+// apigen:api yaml: url: /item/create, method: POST, success_status: 201
+func (srv *Api) Create(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+/* apigen:api+yaml
+url: /item/update
+method: GET,POST
+# The key is read from ITEM_KEY
+auth: true
+auth_env_key: 'ITEM_KEY'
+*/
+func (srv *Api) Update(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+// apigen:api yaml: "url": "\x2Fitem\x2Ffind", 'method': POST # quoted keys and escapes
+func (srv *Api) Find(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestYAMLConfig(t *testing.T) {
+	t.Setenv("ITEM_KEY", "secret")
+	for _, c := range []struct {
+		method, path, auth string
+		status             int
+	}{
+		{http.MethodPost, "/item/create", "", http.StatusCreated},
+		{http.MethodGet, "/item/create?name=box", "", http.StatusNotAcceptable},
+		{http.MethodPost, "/item/update", "", http.StatusForbidden},
+		{http.MethodPost, "/item/update", "secret", http.StatusOK},
+		{http.MethodGet, "/item/update?name=box", "secret", http.StatusOK},
+		{http.MethodGet, "/item/get?name=box", "", http.StatusOK},
+		{http.MethodPost, "/item/find", "", http.StatusOK},
+	} {
+		if status := serve(c.method, c.path, c.auth); status != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.status, status)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateLax(t *testing.T) {
+	files := map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		// GetParams is declared in a file that is not passed to the generator
+		"params.go": `package generated
+
+type GetParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLax(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the handler not to validate parameters, got status %d", w.Code)
+	}
+}
+`,
+	}
+
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(files["api.go"]), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+	warning := "input type GetParams not found for method Get"
+	expected := inputFile + ":10: " + warning
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	var warnings []string
+	warn := func(warning string) {
+		warnings = append(warnings, warning)
+	}
+	testGeneratedPackage(t, generator.Options{Lax: true, Warn: warn}, files)
+	if len(warnings) != 1 || !strings.HasSuffix(warnings[0], string(filepath.Separator)+"api.go:10: "+warning) {
+		t.Errorf("expected a warning about GetParams, got %q", warnings)
+	}
+	// Without Warn, the warning is dropped and the handler still generated
+	err = generator.GenerateWithOptions(inputFile, filepath.Join(dir, "out.go"), generator.Options{Lax: true})
+	if err != nil {
+		t.Errorf("expected lax generation without Warn to pass, got %v", err)
+	}
+
+	_, err = generator.DumpPackage([]string{inputFile}, generator.Options{})
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected dump error %q, got %v", expected, err)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("./generator", "-dump", "-lax", inputFile)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("-dump -lax failed: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "warning: "+inputFile+":10: "+warning) {
+		t.Errorf("expected -dump -lax to log the warning, got %q", stderr.String())
+	}
+	var methods []generator.Method
+	err = json.Unmarshal(out, &methods)
+	if err != nil || len(methods) != 1 || methods[0].Name != "Get" {
+		t.Errorf("expected the method Get to be dumped, got %s, %v", out, err)
+	}
+
+	_, err = generator.LintPackage([]string{inputFile}, generator.Options{})
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected lint error %q, got %v", expected, err)
+	}
+	out, err = exec.Command("./generator", "-lint-strict", "-lax", inputFile).CombinedOutput()
+	if err != nil {
+		t.Errorf("expected -lint-strict -lax to pass, got %v:\n%s", err, out)
+	}
+}
+
+func TestGenerateSuccessStatus(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type ItemParams struct {
+	Name string
+}
+
+type Item struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// apigen:api {"url": "/item/create", "method": "POST", "success_status": 201}
+func (srv *Api) Create(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{Name: in.Name}, nil
+}
+
+// apigen:api {"url": "/item/delete", "method": "POST", "success_status": 204}
+func (srv *Api) Delete(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{Name: in.Name}, nil
+}
+`,
+		"api_test.go": `package generated_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "generated"
+	. "generated/client"
+)
+
+func post(path string) *httptest.ResponseRecorder {
+	body := url.Values{"name": {"box"}}.Encode()
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, r)
+	return w
+}
+
+func TestSuccessStatus(t *testing.T) {
+	w := post("/item/create")
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+	if want := ` + "`{\"error\":\"\",\"response\":{\"name\":\"box\"}}`" + `; strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("expected body %s, got %s", want, w.Body)
+	}
+
+	w = post("/item/delete")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no body, got %s", w.Body)
+	}
+}
+
+func TestClientSuccessStatus(t *testing.T) {
+	ts := httptest.NewServer(&Api{})
+	defer ts.Close()
+	client := NewApiClient(ts.URL, "")
+
+	item, err := client.Create(context.Background(), ItemParams{Name: "box"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if item.Name != "box" {
+		t.Errorf("expected name box, got %q", item.Name)
+	}
+
+	_, err = client.Delete(context.Background(), ItemParams{Name: "box"})
+	if err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+}
+`,
+	})
+}
+
+func TestGenerateRequestID(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{RequestID: true}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+
+type Item struct {
+	RequestID string ` + "`json:\"request_id\"`" + `
+}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{RequestID: RequestIDFromContext(ctx)}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	for _, url := range []string{"/item/get?name=box", "/item/get", "/unknown"} {
+		w := httptest.NewRecorder()
+		(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if id := w.Header().Get("X-Request-ID"); len(id) != 36 {
+			t.Errorf("%s: expected a generated request ID in the response, got %q", url, id)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/item/get?name=box", nil)
+	r.Header.Set("X-Request-ID", "abc-123")
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, r)
+	if id := w.Header().Get("X-Request-ID"); id != "abc-123" {
+		t.Errorf("expected the request ID to be echoed, got %q", id)
+	}
+	if !strings.Contains(w.Body.String(), "\"request_id\":\"abc-123\"") {
+		t.Errorf("expected the method to get the request ID, got %s", w.Body)
+	}
+}
+`,
+	})
+}
+
+func TestGenerateSlog(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{Slog: true, Logging: true}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct{}
+
+type Item struct {
+	RequestID string ` + "`json:\"request_id\"`" + `
+}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	LoggerFromContext(ctx).Info("getting item")
+	return &Item{RequestID: RequestIDFromContext(ctx)}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, requestID string) (*httptest.ResponseRecorder, string) {
+	r := httptest.NewRequest(http.MethodGet, "/item/get", nil)
+	if requestID != "" {
+		r.Header.Set("X-Request-ID", requestID)
+	}
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, r)
+
+	var body struct {
+		Response Item
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("cant unpack %s: %v", w.Body, err)
+	}
+	return w, body.Response.RequestID
+}
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	w, id := get(t, "abc-123")
+	if got := w.Header().Get("X-Request-ID"); got != "abc-123" || id != "abc-123" {
+		t.Errorf("expected request ID abc-123 in the header and context, got %q and %q", got, id)
+	}
+	for _, expected := range []string{"msg=\"getting item\" request_id=abc-123", "msg=request request_id=abc-123 method=GET path=/item/get status=200"} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("expected logs to contain %q, got:\n%s", expected, logs.String())
+		}
+	}
+
+	w, id = get(t, "")
+	uuid := regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+	if got := w.Header().Get("X-Request-ID"); !uuid.MatchString(got) || id != got {
+		t.Errorf("expected a generated UUID in the header and context, got %q and %q", got, id)
+	}
+	if _, other := get(t, ""); other == id {
+		t.Errorf("expected a new request ID for every request, got %q twice", id)
+	}
+
+	if id := RequestIDFromContext(context.Background()); id != "" {
+		t.Errorf("expected no request ID outside requests, got %q", id)
+	}
+	if LoggerFromContext(context.Background()) != slog.Default() {
+		t.Errorf("expected the default logger outside requests")
+	}
+}
+`,
+	})
+}
+
+func TestGenerateStdin(t *testing.T) {
+	src, err := os.Open("example/api.go")
+	if err != nil {
+		t.Fatalf("cant open api.go: %v", err)
+	}
+	defer src.Close()
+
+	cmd := exec.Command("./generator", "-input", "-", "-pkg", "api")
+	cmd.Stdin = src
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("generator failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"// Code generated by gonerator from <stdin>; DO NOT EDIT.\n\npackage api\n",
+		"func (h *MyApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("expected output to contain %q", expected)
+		}
+	}
+}
+
+func TestGenerateNegativeUnsignedBound(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	ID uint64 `+"`"+`apivalidator:"min=-1"`+"`"+`
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	expected := inputFile + ":8: field GetParams.ID: invalid apivalidator tag: min must be >= 0 for uint64, got -1"
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateInvalidIntegerEnum(t *testing.T) {
+	cases := map[string]string{
+		`enum=1|two`:          `enum values must be uint8, got "two"`,
+		`enum=1|256`:          `enum values must be uint8, got "256"`,
+		`enum_ci=1|2`:         "enum_ci can only be used on string fields, got uint8",
+		`uuid`:                "uuid can only be used on string fields, got uint8",
+		`datetime=2006-01-02`: "datetime can only be used on string and time.Time fields, got uint8",
+		`datetime`:            "datetime must be a time layout like 2006-01-02",
+		`min=0.5`:             "min must be an integer for uint8, got 0.5",
+		`max_ex=2.5`:          "max_ex must be an integer for uint8, got 2.5",
+		`enum=1|2,default=03`: `default "03" must be one of [1, 2]`,
+		// The default is compared numerically
+		`enum=1|2,default=02`: "",
+	}
+	for tag, expected := range cases {
+		dir := t.TempDir()
+		inputFile := filepath.Join(dir, "api.go")
+		err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Level uint8 `+"`"+`apivalidator:"`+tag+`"`+"`"+`
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+		if err != nil {
+			t.Fatalf("cant write api.go: %v", err)
+		}
+
+		err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+		if expected == "" {
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", tag, err)
+			}
+			continue
+		}
+		expected = inputFile + ":8: field GetParams.Level: invalid apivalidator tag: " + expected
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", tag, expected, err)
+		}
+	}
+}
+
+func TestGenerateCustomValidatorCollected(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{CollectErrors: true}, map[string]string{
+		"api.go": `package generated
+
+import (
+	"context"
+	"errors"
+)
+
+type Api struct{}
+
+func (srv *Api) ValidateName(name string) error {
+	if name == "root" {
+		return errors.New("name root is taken")
+	}
+	return nil
+}
+
+func (srv Api) ValidateCount(count int) error {
+	if count%2 != 0 {
+		return errors.New("odd")
+	}
+	return nil
+}
+
+type GetParams struct {
+	Name  string ` + "`apivalidator:\"custom=ValidateName\"`" + `
+	Count int    ` + "`apivalidator:\"custom=ValidateCount,msg=count must be even\"`" + `
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCustomValidators(t *testing.T) {
+	cases := []struct {
+		Query  string
+		Status int
+		Body   string
+	}{
+		{"name=alice&count=2", http.StatusOK, ""},
+		{"name=root&count=3", http.StatusBadRequest, "{\"errors\":[\"name root is taken\",\"count must be even\"]}"},
+	}
+	for _, item := range cases {
+		w := httptest.NewRecorder()
+		(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?"+item.Query, nil))
+		if w.Code != item.Status {
+			t.Errorf("%s: expected status %d, got %d", item.Query, item.Status, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); item.Body != "" && body != item.Body {
+			t.Errorf("%s: expected body %s, got %s", item.Query, item.Body, body)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateInvalidCustomValidator(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Name string `+"`"+`apivalidator:"custom=srv.Check"`+"`"+`
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	expected := inputFile + ":8: field GetParams.Name: invalid apivalidator tag: custom must be a method name, got \"srv.Check\""
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateInvalidRequiredIf(t *testing.T) {
+	cases := []struct {
+		Fields string
+		Error  string
+	}{
+		{
+			Fields: "Code string `apivalidator:\"required_if=has_code=true\"`",
+			Error:  "required_if of GetParams.Code refers to unknown parameter has_code",
+		},
+		{
+			Fields: "Code string `apivalidator:\"required_if=level=high\"`\n\tLevel int",
+			Error:  `required_if of GetParams.Code: level must be int, got "high"`,
+		},
+		{
+			Fields: "Code string `apivalidator:\"required_if=since=2024-01-01\"`\n\tSince time.Time `apivalidator:\"datetime=2006-01-02\"`",
+			Error:  "required_if of GetParams.Code: cannot compare with time.Time parameter since",
+		},
+		{
+			Fields: "Code string `apivalidator:\"required_if=ratio=NaN\"`\n\tRatio float64",
+			Error:  `required_if of GetParams.Code: ratio must be a finite number, got "NaN"`,
+		},
+	}
+
+	for _, item := range cases {
+		dir := t.TempDir()
+		inputFile := filepath.Join(dir, "api.go")
+		err := os.WriteFile(inputFile, []byte(`package example
+
+import (
+	"context"
+	"time"
+)
+
+var _ time.Time
+
+type Api struct{}
+
+type GetParams struct {
+	`+item.Fields+`
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+		if err != nil {
+			t.Fatalf("cant write api.go: %v", err)
+		}
+
+		err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+		if err == nil || !strings.HasSuffix(err.Error(), ": method Get: "+item.Error) {
+			t.Errorf("[%s] expected error %q, got %v", item.Fields, item.Error, err)
+		}
+	}
+}
+
+func TestGenerateRequiredIfNumbers(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Level  int     ` + "`apivalidator:\"paramname=level\"`" + `
+	Ratio  float64 ` + "`apivalidator:\"paramname=ratio\"`" + `
+	Reason string  ` + "`apivalidator:\"required_if=level=08\"`" + `
+	Note   string  ` + "`apivalidator:\"required_if=ratio=0.50\"`" + `
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{}, nil
+}
+`,
+		"api_test.go": `package generated
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequiredIfNumbers(t *testing.T) {
+	for query, code := range map[string]int{
+		"level=7":            http.StatusOK,
+		"level=8":            http.StatusBadRequest,
+		"level=8&reason=why": http.StatusOK,
+		"ratio=0.5":          http.StatusBadRequest,
+		"ratio=0.5&note=ok":  http.StatusOK,
+		"ratio=0.25":         http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		(&Api{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/get?"+query, nil))
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d %s", query, code, w.Code, w.Body)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateValidatorsAtPackageLevel(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.Generate("example/api.go", outputFile)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, outputFile, nil, 0)
+	if err != nil {
+		t.Fatalf("cant parse output: %v", err)
+	}
+
+	// Regexes and enum sets must be built once, not on every request
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !strings.HasPrefix(fn.Name.Name, "handler") {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if ident, ok := n.X.(*ast.Ident); ok && ident.Name == "regexp" {
+					t.Errorf("%s: regexp.%s called per request", fset.Position(n.Pos()), n.Sel.Name)
+				}
+			case *ast.CompositeLit:
+				if isConstantSet(n) {
+					t.Errorf("%s: constant set built per request", fset.Position(n.Pos()))
+				}
+			}
+			return true
+		})
+	}
+
+	for _, name := range []string{"regexProductApiCreateSku", "enumMyApiCreateStatus", "enumProductApiListSort"} {
+		if file.Scope.Lookup(name) == nil {
+			t.Errorf("expected package-level var %s", name)
+		}
+	}
+}
+
+// isConstantSet reports whether lit is a slice or map literal of constants only.
+func isConstantSet(lit *ast.CompositeLit) bool {
+	switch lit.Type.(type) {
+	case *ast.ArrayType, *ast.MapType:
+	default:
+		return false
+	}
+	for _, elt := range lit.Elts {
+		exprs := []ast.Expr{elt}
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			exprs = []ast.Expr{kv.Key, kv.Value}
+		}
+		for _, expr := range exprs {
+			switch expr := expr.(type) {
+			case *ast.BasicLit:
+			case *ast.Ident:
+				if expr.Name != "true" {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+	}
+	return len(lit.Elts) > 0
+}
+
+func TestGenerateStrictMethods(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{StrictMethods: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		`w.Header().Set("Allow", "GET, POST")`,
+		`w.Header().Set("Allow", "PUT")`,
+		"http.StatusMethodNotAllowed",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+	if strings.Contains(string(code), "http.StatusNotAcceptable") {
+		t.Errorf("expected output not to use http.StatusNotAcceptable, got:\n%s", code)
+	}
+}
+
+func TestGenerateNoRecover(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{NoRecover: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, unwanted := range []string{"recover()", `"log"`} {
+		if strings.Contains(string(code), unwanted) {
+			t.Errorf("expected output not to contain %q, got:\n%s", unwanted, code)
+		}
+	}
+}
+
+func TestGenerateLogging(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{Logging: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		`"time"`,
+		"type statusWriterMyApi struct",
+		"sw := &statusWriterMyApi{ResponseWriter: w, status: http.StatusOK}",
+		`log.Printf("%s %s %d %s", r.Method, r.URL.Path, sw.status, time.Since(start))`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+}
+
+// routerFixture is an API served through the routers of -router, and
+// instrumented by -metrics and -otel.
+const routerFixture = `package generated
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	ID int
+}
+
+type CreateParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+
+type Item struct {
+	ID   int    ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// apigen:api {"url": "/items/{id}"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Item, error) {
+	return &Item{ID: in.ID}, nil
+}
+
+// apigen:api {"url": "/item/create", "method": "POST"}
+func (srv *Api) Create(ctx context.Context, in CreateParams) (*Item, error) {
+	return &Item{Name: in.Name}, nil
+}
+`
+
+// routerCasesFixture lists the requests served through a router for
+// routerFixture, and what the router must answer.
+const routerCasesFixture = `package generated
+
+import "net/http"
+
+var routerCases = []struct {
+	method, path string
+	status       int
+	body         string
+}{
+	{http.MethodGet, "/items/7", http.StatusOK, ` + "`\"id\":7`" + `},
+	{http.MethodPost, "/item/create", http.StatusOK, ` + "`\"name\":\"box\"`" + `},
+	{http.MethodGet, "/item/create", http.StatusMethodNotAllowed, ""},
+	{http.MethodGet, "/unknown", http.StatusNotFound, ""},
+}
+`
+
+func TestGenerateMetrics(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{Metrics: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		`"github.com/prometheus/client_golang/prometheus/promauto"`,
+		"func MetricsHandler() http.Handler",
+		"apiRequestsTotal.WithLabelValues(r.URL.Path, strconv.Itoa(sw.status)).Inc()",
+		"apiRequestDuration.WithLabelValues(r.URL.Path).Observe(time.Since(start).Seconds())",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+
+	// Without -metrics the generated code must not depend on Prometheus
+	err = generator.Generate("example/api.go", outputFile)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	code, err = os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	if strings.Contains(string(code), "prometheus") {
+		t.Errorf("expected output not to import prometheus, got:\n%s", code)
+	}
+
+	testGeneratedPackage(t, generator.Options{Metrics: true}, map[string]string{
+		"api.go": routerFixture,
+		"api_test.go": `package generated
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	serve("GET", "/items/7", "")
+	serve("GET", "/items/7", "")
+	serve("GET", "/item/create", "")
+
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		` + "`api_requests_total{path=\"/items/7\",status=\"200\"} 2`" + `,
+		` + "`api_requests_total{path=\"/item/create\",status=\"406\"} 1`" + `,
+		` + "`api_request_duration_seconds_count{path=\"/items/7\"} 2`" + `,
+		` + "`api_request_duration_seconds_count{path=\"/item/create\"} 1`" + `,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected the metrics to contain %s, got:\n%s", want, w.Body)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateOtel(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{Otel: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		`"go.opentelemetry.io/otel"`,
+		`var tracer = otel.Tracer("example")`,
+		`tracer.Start(r.Context(), r.Method+" /user/profile"`,
+		"span.RecordError(err)",
+		`attribute.Int("http.response.status_code", sw.status)`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+
+	// Without -otel the generated code must not depend on OpenTelemetry
+	err = generator.Generate("example/api.go", outputFile)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	code, err = os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	if strings.Contains(string(code), "opentelemetry") {
+		t.Errorf("expected output not to import opentelemetry, got:\n%s", code)
+	}
+
+	testGeneratedPackage(t, generator.Options{Otel: true}, map[string]string{
+		"api.go": routerFixture,
+		"api_test.go": `package generated
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOtel(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	serve("GET", "/items/7", "")
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /items/{id}" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected a server span GET /items/{id}, got %s %s", span.SpanKind(), span.Name())
+	}
+	want := map[attribute.Key]attribute.Value{
+		"http.request.method":       attribute.StringValue("GET"),
+		"http.route":                attribute.StringValue("/items/{id}"),
+		"http.response.status_code": attribute.IntValue(200),
+	}
+	for _, attr := range span.Attributes() {
+		if value, ok := want[attr.Key]; ok && value == attr.Value {
+			delete(want, attr.Key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("expected the span attributes to contain %v, got %v", want, span.Attributes())
+	}
+}
+`,
+	})
+}
+
+func TestGenerateChiRouter(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{Router: generator.RouterChi})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		`"github.com/go-chi/chi/v5"`,
+		"func RegisterMyApiChi(r chi.Router, srv *MyApi) {",
+		`r.Method("GET", "/user/profile", srv)`,
+		`r.Method("POST", "/user/profile", srv)`,
+		`r.Method("GET", "/user/{login}", srv)`,
+		`r.Method("PUT", "/product/update", srv)`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+
+	testGeneratedPackage(t, generator.Options{Router: generator.RouterChi}, map[string]string{
+		"api.go":         routerFixture,
+		"routes_test.go": routerCasesFixture,
+		"api_test.go": `package generated
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestChiRouter(t *testing.T) {
+	r := chi.NewRouter()
+	RegisterApiChi(r, &Api{})
+	for _, c := range routerCases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader("name=box"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.status || !strings.Contains(w.Body.String(), c.body) {
+			t.Errorf("%s %s: expected %d with %q, got %d with %q", c.method, c.path, c.status, c.body, w.Code, w.Body)
+		}
+	}
+}
+`,
+	})
+
+	err = generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{Router: "echo"})
+	if err == nil || err.Error() != `unsupported router "echo"` {
+		t.Errorf("expected unsupported router error, got %v", err)
+	}
+}
+
+func TestGenerateGinRouter(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.go")
+	err := generator.GenerateWithOptions("example/api.go", outputFile, generator.Options{Router: generator.RouterGin})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	code, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("cant read output: %v", err)
+	}
+	for _, want := range []string{
+		`"github.com/gin-gonic/gin"`,
+		"func RegisterMyApiGin(r gin.IRouter, srv *MyApi) {",
+		"handler := gin.WrapH(srv)",
+		`r.Handle("GET", "/user/profile", handler)`,
+		`r.Handle("GET", "/user/:login", handler)`,
+		`r.Handle("DELETE", "/product/delete", handler)`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, code)
+		}
+	}
+	if strings.Contains(string(code), "go-chi") {
+		t.Errorf("expected output not to depend on chi, got:\n%s", code)
+	}
+
+	testGeneratedPackage(t, generator.Options{Router: generator.RouterGin}, map[string]string{
+		"api.go":         routerFixture,
+		"routes_test.go": routerCasesFixture,
+		"api_test.go": `package generated
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.HandleMethodNotAllowed = true
+	RegisterApiGin(r, &Api{})
+	for _, c := range routerCases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader("name=box"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.status || !strings.Contains(w.Body.String(), c.body) {
+			t.Errorf("%s %s: expected %d with %q, got %d with %q", c.method, c.path, c.status, c.body, w.Code, w.Body)
+		}
+	}
+}
+`,
+	})
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	dir := t.TempDir()
+	err := generator.GenerateWithOptions("test/testdata/jsonschema/api.go", filepath.Join(dir, "out.go"), generator.Options{
+		JSONSchemaDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions failed: %v", err)
+	}
+
+	schema, err := os.ReadFile(generator.JSONSchemaPath(dir, "SearchParams"))
+	if err != nil {
+		t.Fatalf("cant read schema: %v", err)
+	}
+	golden, err := os.ReadFile("test/testdata/jsonschema/SearchParams.schema.json")
+	if err != nil {
+		t.Fatalf("cant read golden schema: %v", err)
+	}
+	if string(schema) != string(golden) {
+		t.Errorf("schema does not match golden file\nGot:\n%s\nExpected:\n%s", schema, golden)
+	}
+}
+
+func TestGeneratePostman(t *testing.T) {
+	dir := t.TempDir()
+	collectionFile := filepath.Join(dir, "collection.json")
+	err := generator.GenerateWithOptions("test/testdata/postman/api.go", filepath.Join(dir, "out.go"), generator.Options{
+		PostmanFile: collectionFile,
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions failed: %v", err)
+	}
+
+	collection, err := os.ReadFile(collectionFile)
+	if err != nil {
+		t.Fatalf("cant read collection: %v", err)
+	}
+	golden, err := os.ReadFile("test/testdata/postman/collection.json")
+	if err != nil {
+		t.Fatalf("cant read golden collection: %v", err)
+	}
+	if string(collection) != string(golden) {
+		t.Errorf("collection does not match golden file\nGot:\n%s\nExpected:\n%s", collection, golden)
+	}
+}
+
+func TestGenerateClientZeroValues(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	ID    int
+	Limit int    ` + "`apivalidator:\"default=10\"`" + `
+	Sort  string ` + "`apivalidator:\"default=name\"`" + `
+}
+
+type Page struct {
+	ID    int    ` + "`json:\"id\"`" + `
+	Limit int    ` + "`json:\"limit\"`" + `
+	Sort  string ` + "`json:\"sort\"`" + `
+}
+
+// apigen:api {"url": "/items/{id}"}
+func (srv *Api) Get(ctx context.Context, in GetParams) (*Page, error) {
+	return &Page{ID: in.ID, Limit: in.Limit, Sort: in.Sort}, nil
+}
+`,
+		"api_test.go": `package generated_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	. "generated"
+	. "generated/client"
+)
+
+func TestClientZeroValues(t *testing.T) {
+	ts := httptest.NewServer(&Api{})
+	defer ts.Close()
+	c := NewApiClient(ts.URL, "")
+
+	// A zero path parameter is sent, and zero parameters with a default
+	// are left to the server unless listed in set
+	page, err := c.Get(context.Background(), GetParams{})
+	if err != nil || *page != (Page{ID: 0, Limit: 10, Sort: "name"}) {
+		t.Errorf("expected the defaults, got %+v, %v", page, err)
+	}
+	page, err = c.Get(context.Background(), GetParams{ID: 3}, "limit", "sort")
+	if err != nil || *page != (Page{ID: 3, Limit: 0, Sort: ""}) {
+		t.Errorf("expected the zero values to be sent, got %+v, %v", page, err)
+	}
+}
+`,
+	})
+}
+
+func TestGenerateClientErrors(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module generated\n\ngo 1.22\n"), 0644)
+	if err != nil {
+		t.Fatalf("cant write go.mod: %v", err)
+	}
+	err = os.WriteFile(inputFile, []byte(`package generated
+
+import "context"
+
+type Api struct{}
+
+type getParams struct {
+	Name string
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in getParams) (*Item, error) {
+	return &Item{}, nil
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	for _, c := range []struct {
+		ClientFile string
+		Error      string
+	}{
+		{filepath.Join(dir, "client.go"), "the client must be written to another directory than " + dir + ", the package of the handlers"},
+		{filepath.Join(dir, "api-client", "client.go"), `invalid client package name "api-client", the client must be written to a directory named like a Go package`},
+		{filepath.Join(dir, "client", "client.go"), "method Get: input type getParams is not exported, so the client cannot use it"},
+	} {
+		err := generator.GenerateWithOptions(inputFile, filepath.Join(dir, "api_gen.go"), generator.Options{ClientFile: c.ClientFile})
+		if err == nil || err.Error() != c.Error {
+			t.Errorf("%s: expected error %q, got %v", c.ClientFile, c.Error, err)
+		}
+	}
+}
+
+func TestGenerateResultTypes(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type ItemParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+
+type Item struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// apigen:api {"url": "/item/get", "method": "GET"}
+func (srv *Api) Get(ctx context.Context, in ItemParams) (Item, error) {
+	return Item{Name: in.Name}, nil
+}
+
+// apigen:api {"url": "/item/list", "method": "GET"}
+func (srv *Api) List(ctx context.Context, in ItemParams) ([]*Item, error) {
+	return []*Item{{Name: in.Name}, {Name: in.Name + "2"}}, nil
+}
+
+// apigen:api {"url": "/item/counts", "method": "GET"}
+func (srv *Api) Counts(ctx context.Context, in ItemParams) (map[string]int, error) {
+	return map[string]int{in.Name: 1}, nil
+}
+`,
+		"api_test.go": `package generated_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	. "generated"
+	. "generated/client"
+)
+
+func TestResultTypes(t *testing.T) {
+	ts := httptest.NewServer(&Api{})
+	defer ts.Close()
+	c := NewApiClient(ts.URL, "")
+
+	item, err := c.Get(context.Background(), ItemParams{Name: "box"})
+	if err != nil || item.Name != "box" {
+		t.Errorf("Get: expected box, got %+v, %v", item, err)
+	}
+
+	items, err := c.List(context.Background(), ItemParams{Name: "box"})
+	if err != nil || len(items) != 2 || items[0].Name != "box" || items[1].Name != "box2" {
+		t.Errorf("List: expected box and box2, got %+v, %v", items, err)
+	}
+
+	counts, err := c.Counts(context.Background(), ItemParams{Name: "box"})
+	if err != nil || counts["box"] != 1 {
+		t.Errorf("Counts: expected box: 1, got %v, %v", counts, err)
+	}
+
+	item, err = c.Get(context.Background(), ItemParams{})
+	if err == nil || item != (Item{}) {
+		t.Errorf("Get: expected an error and no item, got %+v, %v", item, err)
+	}
+}
+`,
+	})
+}
+
+func TestGenerateXMLMapResult(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+import "context"
+
+type Api struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/counts"}
+func (srv *Api) Counts(ctx context.Context, in Item) (map[string]int, error) {
+	panic("not implemented")
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	expected := "method Counts: result type map[string]int cannot be written as XML"
+	err = generator.GenerateWithOptions(inputFile, filepath.Join(dir, "out.go"), generator.Options{XML: true})
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGenerateDefaultParamNames(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{}, map[string]string{
+		"api.go": `package generated
+
+import "context"
+
+type Api struct{}
+
+type SearchParams struct {
+	Login    string ` + "`apivalidator:\"required\"`" + `
+	FullName string
+	UserID   int
+	Nick     string ` + "`apivalidator:\"paramname=nickName\"`" + `
+}
+
+type Result struct {
+	Login    string ` + "`json:\"login\"`" + `
+	FullName string ` + "`json:\"full_name\"`" + `
+	UserID   int    ` + "`json:\"user_id\"`" + `
+	Nick     string ` + "`json:\"nick\"`" + `
+}
+
+// apigen:api {"url": "/search", "method": "GET"}
+func (srv *Api) Search(ctx context.Context, in SearchParams) (*Result, error) {
+	return &Result{Login: in.Login, FullName: in.FullName, UserID: in.UserID, Nick: in.Nick}, nil
+}
+`,
+		"api_test.go": `package generated_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "generated"
+	. "generated/client"
+)
+
+func TestDefaultParamNames(t *testing.T) {
+	for _, tc := range []struct {
+		Query string
+		Code  int
+		Body  string
+	}{
+		{"login=bob&full_name=Bob+Smith&user_id=7&nickName=bobby", http.StatusOK, ` + "`{\"error\":\"\",\"response\":{\"login\":\"bob\",\"full_name\":\"Bob Smith\",\"user_id\":7,\"nick\":\"bobby\"}}`" + `},
+		{"login=bob&fullname=Bob&FullName=Bob&userid=7&nick=bobby", http.StatusOK, ` + "`{\"error\":\"\",\"response\":{\"login\":\"bob\",\"full_name\":\"\",\"user_id\":0,\"nick\":\"\"}}`" + `},
+		{"Login=bob", http.StatusBadRequest, ` + "`{\"error\": \"login must be not empty\", \"code\": \"VALIDATION_ERROR\"}`" + `},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/search?"+tc.Query, nil)
+		w := httptest.NewRecorder()
+		(&Api{}).ServeHTTP(w, r)
+		if w.Code != tc.Code || w.Body.String() != tc.Body+"\n" {
+			t.Errorf("%s: expected %d %s, got %d %s", tc.Query, tc.Code, tc.Body, w.Code, w.Body)
+		}
+	}
+
+	ts := httptest.NewServer(&Api{})
+	defer ts.Close()
+	in := SearchParams{Login: "bob", FullName: "Bob Smith", UserID: 7, Nick: "bobby"}
+	res, err := NewApiClient(ts.URL, "").Search(context.Background(), in)
+	if err != nil || *res != (Result{"bob", "Bob Smith", 7, "bobby"}) {
+		t.Errorf("Search: expected %+v, got %+v, %v", in, res, err)
+	}
+}
+`,
+	})
+}
+
+func TestGenerateWithoutContext(t *testing.T) {
+	testGeneratedPackage(t, generator.Options{MocksFile: "api_mock.go"}, map[string]string{
+		"api.go": `package generated
+
+import "net/http"
+
+type Api struct{}
+
+type PingParams struct {
+	Name string ` + "`apivalidator:\"required\"`" + `
+}
+
+type Pong struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Agent string ` + "`json:\"agent\"`" + `
+}
+
+// apigen:api {"url": "/ping", "method": "GET"}
+func (srv *Api) Ping(in PingParams) (*Pong, error) {
+	return &Pong{Name: in.Name}, nil
+}
+
+// apigen:api {"url": "/ping/agent", "method": "GET"}
+func (srv *Api) PingAgent(in PingParams, r *http.Request) (*Pong, error) {
+	return &Pong{Name: in.Name, Agent: r.UserAgent()}, nil
+}
+`,
+		"api_test.go": `package generated_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "generated"
+	. "generated/client"
+)
+
+func TestWithoutContext(t *testing.T) {
+	ts := httptest.NewServer(&Api{})
+	defer ts.Close()
+	c := NewApiClient(ts.URL, "")
+
+	pong, err := c.Ping(context.Background(), PingParams{Name: "box"})
+	if err != nil || pong.Name != "box" {
+		t.Errorf("Ping: expected box, got %+v, %v", pong, err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/ping/agent?name=box", nil)
+	r.Header.Set("User-Agent", "tester")
+	w := httptest.NewRecorder()
+	(&Api{}).ServeHTTP(w, r)
+	if want := ` + "`{\"error\":\"\",\"response\":{\"name\":\"box\",\"agent\":\"tester\"}}`" + `; w.Code != http.StatusOK || w.Body.String() != want+"\n" {
+		t.Errorf("PingAgent: expected %s, got %d %s", want, w.Code, w.Body)
+	}
+
+	var api ApiInterface = &ApiMock{PingResult: &Pong{Name: "mock"}}
+	if pong, _ := api.Ping(PingParams{}); pong.Name != "mock" {
+		t.Errorf("ApiMock.Ping: expected mock, got %+v", pong)
+	}
+}
+`,
+	})
+}
+
+func TestGenerateTimeoutWithoutContext(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "api.go")
+	err := os.WriteFile(inputFile, []byte(`package example
+
+type Api struct{}
+
+type Item struct{}
+
+// apigen:api {"url": "/item/get", "timeout_ms": 100}
+func (srv *Api) Get(in Item) (*Item, error) {
+	panic("not implemented")
+}
+`), 0644)
+	if err != nil {
+		t.Fatalf("cant write api.go: %v", err)
+	}
+
+	expected := inputFile + ":7: method Get: timeout_ms requires a context.Context parameter"
+	err = generator.Generate(inputFile, filepath.Join(dir, "out.go"))
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/notrightending/gonerator/example"
+)
+
+func TestMyApiMock(t *testing.T) {
+	ctx := context.Background()
+
+	var api example.MyApiInterface = &example.MyApiMock{
+		ProfileResult: &example.User{ID: 7, Login: "mock"},
+	}
+	user, err := api.Profile(ctx, example.ProfileParams{Login: "mock"})
+	if err != nil || user.ID != 7 {
+		t.Errorf("expected programmed result, got %+v, %v", user, err)
+	}
+
+	m := &example.MyApiMock{
+		CreateFunc: func(ctx context.Context, in example.CreateParams) (*example.NewUser, error) {
+			if in.Login == "taken" {
+				return nil, errors.New("taken")
+			}
+			return &example.NewUser{ID: 100}, nil
+		},
+	}
+	created, err := m.Create(ctx, example.CreateParams{Login: "fresh"})
+	if err != nil || created.ID != 100 {
+		t.Errorf("expected result of CreateFunc, got %+v, %v", created, err)
+	}
+	_, err = m.Create(ctx, example.CreateParams{Login: "taken"})
+	if err == nil || err.Error() != "taken" {
+		t.Errorf("expected error of CreateFunc, got %v", err)
+	}
+
+	if len(m.CreateCalls) != 2 || m.CreateCalls[0].Login != "fresh" || m.CreateCalls[1].Login != "taken" {
+		t.Errorf("expected calls to be recorded, got %+v", m.CreateCalls)
+	}
+}
@@ -0,0 +1,30 @@
+package jsonschema
+
+import "context"
+
+type Api struct{}
+
+type SearchParams struct {
+	Query  string  `apivalidator:"required,min=3,max=64"`
+	Sort   string  `apivalidator:"enum=name|price,default=name"`
+	Owner  string  `apivalidator:"email"`
+	Sku    string  `apivalidator:"regex=^[A-Z]{3}-\\d+$"`
+	Code   string  `apivalidator:"min_ex=1,max_ex=5"`
+	Limit  int     `apivalidator:"min=1,max=100,multiple_of=10,default=20"`
+	Page   int     `apivalidator:"min_ex=0,max_ex=1000"`
+	Status uint8   `apivalidator:"enum=0|1|2"`
+	Price  float64 `apivalidator:"min=0.01"`
+	Active bool    `apivalidator:"default=true"`
+}
+
+type Result struct{}
+
+// apigen:api {"url": "/search"}
+func (srv *Api) Search(ctx context.Context, in SearchParams) (*Result, error) {
+	return &Result{}, nil
+}
+
+// apigen:api {"url": "/search/count"}
+func (srv *Api) Count(ctx context.Context, in SearchParams) (*Result, error) {
+	return &Result{}, nil
+}
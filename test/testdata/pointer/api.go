@@ -0,0 +1,18 @@
+package pointer
+
+import "context"
+
+type Api struct{}
+
+type GetParams struct {
+	Sku string `apivalidator:"required"`
+}
+
+type Item struct {
+	Sku string `json:"sku"`
+}
+
+// apigen:api {"url": "/item/get"}
+func (srv *Api) Get(ctx context.Context, in *GetParams) (*Item, error) {
+	return &Item{Sku: in.Sku}, nil
+}
@@ -0,0 +1,45 @@
+package postman
+
+import "context"
+
+type Api struct{}
+
+type SearchParams struct {
+	Query string `apivalidator:"required,min=3"`
+	Sort  string `apivalidator:"enum=name|price,default=name"`
+}
+
+type CreateParams struct {
+	Name  string `apivalidator:"required"`
+	Price int    `apivalidator:"min=0,default=100"`
+}
+
+type ItemParams struct {
+	ID string `apivalidator:"required"`
+}
+
+type Item struct{}
+
+// apigen:api {"url": "/items/search", "method": "GET"}
+func (srv *Api) Search(ctx context.Context, in SearchParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+// apigen:api {"url": "/items/create", "method": "POST", "auth": true}
+func (srv *Api) Create(ctx context.Context, in CreateParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+// apigen:api {"url": "/items/{id}", "method": "DELETE", "auth": true, "auth_scheme": "bearer"}
+func (srv *Api) Delete(ctx context.Context, in ItemParams) (*Item, error) {
+	return &Item{}, nil
+}
+
+type AdminApi struct{}
+
+type StatsParams struct{}
+
+// apigen:api {"url": "/admin/stats"}
+func (srv *AdminApi) Stats(ctx context.Context, in StatsParams) (*Item, error) {
+	return &Item{}, nil
+}
@@ -0,0 +1,18 @@
+package qualified
+
+import (
+	"context"
+
+	ex "github.com/notrightending/gonerator/example"
+)
+
+type Api struct{}
+
+type Profile struct {
+	Login string `json:"login"`
+}
+
+// apigen:api {"url": "/user/profile"}
+func (srv *Api) Profile(ctx context.Context, in ex.ProfileParams) (*Profile, error) {
+	return &Profile{Login: in.Login}, nil
+}